@@ -0,0 +1,285 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// migrations creates the helpdesk tables that are not part of the SIK schema.
+// Every statement must be idempotent since it runs on each startup.
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS helpdesk_ticket_comments (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		ticket_id INT NOT NULL,
+		user_id VARCHAR(50) NOT NULL,
+		user_nama VARCHAR(100) NOT NULL DEFAULT '',
+		body TEXT NOT NULL,
+		is_internal TINYINT(1) NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		INDEX idx_ticket_comments_ticket (ticket_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS helpdesk_ticket_events (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		ticket_id INT NOT NULL,
+		event_type VARCHAR(50) NOT NULL,
+		actor_id VARCHAR(50) NOT NULL DEFAULT '',
+		actor_nama VARCHAR(100) NOT NULL DEFAULT '',
+		old_value VARCHAR(255) NULL,
+		new_value VARCHAR(255) NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_ticket_events_ticket (ticket_id, created_at)
+	)`,
+	`CREATE TABLE IF NOT EXISTS helpdesk_sla_policies (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		category_id INT NULL,
+		category VARCHAR(100) NULL,
+		priority VARCHAR(20) NULL,
+		response_minutes INT NOT NULL,
+		resolution_minutes INT NOT NULL,
+		is_active TINYINT(1) NOT NULL DEFAULT 1,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE IF NOT EXISTS helpdesk_routing_rules (
+		category_id INT PRIMARY KEY,
+		category VARCHAR(100) NOT NULL,
+		strategy VARCHAR(30) NOT NULL,
+		last_assignee_id VARCHAR(50) NULL,
+		is_active TINYINT(1) NOT NULL DEFAULT 1,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE IF NOT EXISTS helpdesk_staff_skills (
+		user_id VARCHAR(50) NOT NULL,
+		category_id INT NOT NULL,
+		category VARCHAR(100) NOT NULL,
+		PRIMARY KEY (user_id, category_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS helpdesk_teams (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		name VARCHAR(100) NOT NULL UNIQUE,
+		description VARCHAR(255) NOT NULL DEFAULT '',
+		telegram_chat_id VARCHAR(50) NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE IF NOT EXISTS helpdesk_team_members (
+		team_id INT NOT NULL,
+		user_id VARCHAR(50) NOT NULL,
+		PRIMARY KEY (team_id, user_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS helpdesk_category_fields (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		category_id INT NOT NULL,
+		field_key VARCHAR(50) NOT NULL,
+		label VARCHAR(100) NOT NULL,
+		field_type VARCHAR(20) NOT NULL,
+		options TEXT NULL,
+		is_required TINYINT(1) NOT NULL DEFAULT 0,
+		sort_order INT NOT NULL DEFAULT 0,
+		is_active TINYINT(1) NOT NULL DEFAULT 1,
+		UNIQUE KEY uq_category_field_key (category_id, field_key)
+	)`,
+	`CREATE TABLE IF NOT EXISTS helpdesk_ticket_field_values (
+		ticket_id INT NOT NULL,
+		field_id INT NOT NULL,
+		value TEXT NOT NULL,
+		PRIMARY KEY (ticket_id, field_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS helpdesk_attachments (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		ticket_id INT NOT NULL,
+		comment_id INT NULL,
+		kind VARCHAR(20) NOT NULL DEFAULT 'other',
+		original_name VARCHAR(255) NOT NULL,
+		stored_path VARCHAR(255) NOT NULL,
+		mime_type VARCHAR(100) NOT NULL DEFAULT 'application/octet-stream',
+		size BIGINT NOT NULL DEFAULT 0,
+		uploader_id VARCHAR(50) NOT NULL,
+		uploader_nama VARCHAR(100) NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_attachments_ticket (ticket_id, created_at)
+	)`,
+	`CREATE TABLE IF NOT EXISTS helpdesk_upload_sessions (
+		id CHAR(32) PRIMARY KEY,
+		ticket_id INT NOT NULL,
+		comment_id INT NULL,
+		kind VARCHAR(20) NOT NULL DEFAULT 'other',
+		filename VARCHAR(255) NOT NULL,
+		size BIGINT NOT NULL,
+		received BIGINT NOT NULL DEFAULT 0,
+		checksum CHAR(64) NOT NULL,
+		uploader_id VARCHAR(50) NOT NULL,
+		uploader_nama VARCHAR(100) NOT NULL DEFAULT '',
+		completing_until DATETIME NULL,
+		attachment_id INT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		expires_at DATETIME NOT NULL,
+		INDEX idx_upload_sessions_expires (expires_at)
+	)`,
+	`CREATE TABLE IF NOT EXISTS helpdesk_upload_chunks (
+		session_id CHAR(32) NOT NULL,
+		start_offset BIGINT NOT NULL,
+		size BIGINT NOT NULL,
+		storage_key VARCHAR(255) NOT NULL,
+		PRIMARY KEY (session_id, start_offset)
+	)`,
+	`CREATE TABLE IF NOT EXISTS helpdesk_notification_outbox (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		channel VARCHAR(30) NOT NULL,
+		event VARCHAR(50) NOT NULL,
+		ticket_id INT NULL,
+		payload TEXT NOT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		attempts INT NOT NULL DEFAULT 0,
+		last_error TEXT NULL,
+		next_attempt_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		sent_at DATETIME NULL,
+		INDEX idx_outbox_due (status, next_attempt_at)
+	)`,
+	`CREATE TABLE IF NOT EXISTS helpdesk_admin_audit (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		action VARCHAR(20) NOT NULL,
+		target_id VARCHAR(50) NOT NULL,
+		target_nama VARCHAR(100) NOT NULL DEFAULT '',
+		actor_id VARCHAR(50) NOT NULL,
+		actor_nama VARCHAR(100) NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_admin_audit_target (target_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS helpdesk_telegram_links (
+		user_id VARCHAR(50) PRIMARY KEY,
+		chat_id VARCHAR(50) NOT NULL,
+		telegram_username VARCHAR(100) NOT NULL DEFAULT '',
+		linked_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE KEY uq_telegram_links_chat (chat_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS helpdesk_telegram_link_codes (
+		code VARCHAR(64) PRIMARY KEY,
+		user_id VARCHAR(50) NOT NULL,
+		expires_at DATETIME NOT NULL,
+		INDEX idx_telegram_link_codes_user (user_id)
+	)`,
+}
+
+// columnMigration adds a column to an existing table when it is missing.
+// Backfill, if set, runs once right after the column is added.
+type columnMigration struct {
+	Table      string
+	Column     string
+	Definition string
+	Backfill   string
+}
+
+var columnMigrations = []columnMigration{
+	{"helpdesk_tickets", "first_response_at", "DATETIME NULL", ""},
+	{"helpdesk_tickets", "response_due_at", "DATETIME NULL", ""},
+	{"helpdesk_tickets", "resolution_due_at", "DATETIME NULL", ""},
+	{"helpdesk_tickets", "sla_response_warned", "TINYINT(1) NOT NULL DEFAULT 0", ""},
+	{"helpdesk_tickets", "sla_resolution_warned", "TINYINT(1) NOT NULL DEFAULT 0", ""},
+	{"helpdesk_tickets", "urgency", "VARCHAR(20) NOT NULL DEFAULT 'sedang'", ""},
+	{"helpdesk_tickets", "impact", "VARCHAR(20) NOT NULL DEFAULT 'sedang'", ""},
+	{"helpdesk_tickets", "priority", "VARCHAR(20) NOT NULL DEFAULT 'sedang'", ""},
+	{"helpdesk_tickets", "assignee_id", "VARCHAR(50) NULL", ""},
+	{"helpdesk_ticket_events", "note", "TEXT NULL", ""},
+	{"helpdesk_admins", "is_available", "TINYINT(1) NOT NULL DEFAULT 1", ""},
+	{"helpdesk_categories", "team_id", "INT NULL", ""},
+	{"helpdesk_tickets", "team_id", "INT NULL", ""},
+	{"helpdesk_categories", "sort_order", "INT NOT NULL DEFAULT 0", ""},
+	{"helpdesk_categories", "is_active", "TINYINT(1) NOT NULL DEFAULT 1", ""},
+	{"helpdesk_categories", "parent_id", "INT NULL", ""},
+	{"helpdesk_tickets", "category_id", "INT NULL",
+		"UPDATE helpdesk_tickets t JOIN helpdesk_categories c ON c.name = t.category SET t.category_id = c.id"},
+	// Existing admins had full access before roles existed
+	{"helpdesk_admins", "role", "VARCHAR(30) NOT NULL DEFAULT 'technician'",
+		"UPDATE helpdesk_admins SET role = 'super_admin'"},
+	{"helpdesk_admin_audit", "role", "VARCHAR(30) NULL", ""},
+	{"helpdesk_attachments", "thumbnail_path", "VARCHAR(255) NULL", ""},
+	{"helpdesk_attachments", "scan_status", "VARCHAR(20) NOT NULL DEFAULT 'unscanned'", ""},
+	{"helpdesk_tickets", "bukti_masalah_thumb", "VARCHAR(255) NULL", ""},
+	{"helpdesk_tickets", "bukti_selesai_thumb", "VARCHAR(255) NULL", ""},
+	// Rules refer to categories by ID; the name stays as a label. A policy whose category
+	// is gone is deactivated instead of becoming a wildcard (MySQL assigns left to right).
+	{"helpdesk_sla_policies", "category_id", "INT NULL",
+		`UPDATE helpdesk_sla_policies
+		SET category_id = (SELECT MIN(c.id) FROM helpdesk_categories c WHERE c.name = helpdesk_sla_policies.category),
+			is_active = is_active AND (category IS NULL OR category_id IS NOT NULL)`},
+	{"helpdesk_routing_rules", "category_id", "INT NULL",
+		"UPDATE helpdesk_routing_rules r JOIN helpdesk_categories c ON c.name = r.category SET r.category_id = c.id"},
+	{"helpdesk_staff_skills", "category_id", "INT NULL",
+		"UPDATE helpdesk_staff_skills s JOIN helpdesk_categories c ON c.name = s.category SET s.category_id = c.id"},
+	{"helpdesk_upload_sessions", "completing_until", "DATETIME NULL", ""},
+	{"helpdesk_upload_sessions", "attachment_id", "INT NULL", ""},
+}
+
+// primaryKeyMigration moves the primary key of an existing table to other columns.
+// Prepare, if set, runs first, e.g. to drop rows the new key cannot hold.
+type primaryKeyMigration struct {
+	Table   string
+	Columns string // comma-separated, without spaces
+	Prepare string
+}
+
+var primaryKeyMigrations = []primaryKeyMigration{
+	{"helpdesk_routing_rules", "category_id", "DELETE FROM helpdesk_routing_rules WHERE category_id IS NULL"},
+	{"helpdesk_staff_skills", "user_id,category_id", "DELETE FROM helpdesk_staff_skills WHERE category_id IS NULL"},
+}
+
+// MigrateDatabase creates missing helpdesk tables and columns
+func MigrateDatabase() {
+	for _, stmt := range migrations {
+		if _, err := DB.Exec(stmt); err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
+	}
+	for _, m := range columnMigrations {
+		if err := addColumnIfMissing(m); err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
+	}
+	for _, m := range primaryKeyMigrations {
+		if err := movePrimaryKey(m); err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
+	}
+	log.Println("Database migrated successfully")
+}
+
+func addColumnIfMissing(m columnMigration) error {
+	var count int
+	err := DB.QueryRow(`
+		SELECT COUNT(*) FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?
+	`, m.Table, m.Column).Scan(&count)
+	if err != nil || count > 0 {
+		return err
+	}
+
+	if _, err := DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", m.Table, m.Column, m.Definition)); err != nil {
+		return err
+	}
+	if m.Backfill != "" {
+		_, err = DB.Exec(m.Backfill)
+	}
+	return err
+}
+
+func movePrimaryKey(m primaryKeyMigration) error {
+	var columns sql.NullString
+	err := DB.QueryRow(`
+		SELECT GROUP_CONCAT(COLUMN_NAME ORDER BY ORDINAL_POSITION) FROM information_schema.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND CONSTRAINT_NAME = 'PRIMARY'
+	`, m.Table).Scan(&columns)
+	if err != nil || columns.String == m.Columns {
+		return err
+	}
+
+	if m.Prepare != "" {
+		if _, err := DB.Exec(m.Prepare); err != nil {
+			return err
+		}
+	}
+	_, err = DB.Exec(fmt.Sprintf("ALTER TABLE %s DROP PRIMARY KEY, ADD PRIMARY KEY (%s)", m.Table, m.Columns))
+	return err
+}
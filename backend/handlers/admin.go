@@ -3,35 +3,37 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"helpdesk-backend/config"
+	"helpdesk-backend/models"
 	"helpdesk-backend/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 type AuthResponse struct {
-	UserID  string `json:"user_id"`
-	Nama    string `json:"nama"`
-	IsAdmin bool   `json:"is_admin"`
+	UserID      string   `json:"user_id"`
+	Nama        string   `json:"nama"`
+	IsAdmin     bool     `json:"is_admin"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
 }
 
-// GetAuthInfo - Get current user info including admin status
+// GetAuthInfo - Get current user info including role and permissions
 func GetAuthInfo(c *gin.Context) {
 	userID := c.GetString("user_id")
 	nama := c.GetString("user_nama")
 
-	// Check if user is admin
-	var count int
-	config.DB.QueryRow(`
-		SELECT COUNT(*) FROM helpdesk_admins WHERE user_id = ?
-	`, userID).Scan(&count)
+	role := c.GetString("user_role")
 
 	c.JSON(http.StatusOK, AuthResponse{
-		UserID:  userID,
-		Nama:    nama,
-		IsAdmin: count > 0,
+		UserID:      userID,
+		Nama:        nama,
+		IsAdmin:     role != services.RoleRequester,
+		Role:        role,
+		Permissions: services.RolePermissions(role),
 	})
 }
 
@@ -39,16 +41,17 @@ func GetAuthInfo(c *gin.Context) {
 func GetAllTicketsAdmin(c *gin.Context) {
 	userID := c.GetString("user_id")
 
-	// Check if admin
-	var count int
-	config.DB.QueryRow(`SELECT COUNT(*) FROM helpdesk_admins WHERE user_id = ?`, userID).Scan(&count)
-	if count == 0 {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
-		return
-	}
-
 	// Get query parameters
-	dateFilter := c.DefaultQuery("date", time.Now().Format("2006-01-02")) // Default: today
+	assignee := c.Query("assignee")
+	team := c.Query("team")
+	if assignee == "me" {
+		assignee = userID
+	}
+	// Default: today, unless listing someone's tickets
+	dateFilter := c.Query("date")
+	if dateFilter == "" && assignee == "" {
+		dateFilter = time.Now().Format("2006-01-02")
+	}
 	page := c.DefaultQuery("page", "1")
 	limit := c.DefaultQuery("limit", "20")
 
@@ -67,18 +70,45 @@ func GetAllTicketsAdmin(c *gin.Context) {
 
 	offset := (pageNum - 1) * limitNum
 
+	// Build filters
+	conditions := []string{}
+	args := []interface{}{}
+	if dateFilter != "" {
+		conditions = append(conditions, "DATE(created_at) = ?")
+		args = append(args, dateFilter)
+	}
+	if assignee != "" {
+		conditions = append(conditions, "assignee_id = ?")
+		args = append(args, assignee)
+	}
+	if team != "" {
+		conditions = append(conditions, "team_id = ?")
+		args = append(args, team)
+	}
+	// Custom field filters: field[<key>]=<value>
+	for key, value := range c.QueryMap("field") {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM helpdesk_ticket_field_values v
+			JOIN helpdesk_category_fields f ON f.id = v.field_id
+			WHERE v.ticket_id = helpdesk_tickets.id AND f.field_key = ? AND v.value = ?)`)
+		args = append(args, key, value)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
 	// Get total count for pagination
 	var totalCount int
 	config.DB.QueryRow(`
 		SELECT COUNT(*) FROM helpdesk_tickets 
-		WHERE DATE(created_at) = ?
-	`, dateFilter).Scan(&totalCount)
+		`+where, args...).Scan(&totalCount)
 
 	rows, err := config.DB.Query(`
-		SELECT id, ticket_number, user_id, subject, description, status, category, 
-		       dikerjakan_oleh, bukti_masalah, bukti_selesai, created_at, updated_at, resolved_at
+		SELECT `+ticketColumns+`
 		FROM helpdesk_tickets 
-		WHERE DATE(created_at) = ?
+		`+where+`
 		ORDER BY 
 			CASE status 
 				WHEN 'baru' THEN 1 
@@ -86,9 +116,15 @@ func GetAllTicketsAdmin(c *gin.Context) {
 				WHEN 'selesai' THEN 3 
 				WHEN 'ditutup' THEN 4 
 			END,
+			CASE priority
+				WHEN 'kritis' THEN 1
+				WHEN 'tinggi' THEN 2
+				WHEN 'sedang' THEN 3
+				WHEN 'rendah' THEN 4
+			END,
 			created_at DESC
 		LIMIT ? OFFSET ?
-	`, dateFilter, limitNum, offset)
+	`, append(args, limitNum, offset)...)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -96,36 +132,18 @@ func GetAllTicketsAdmin(c *gin.Context) {
 	}
 	defer rows.Close()
 
-	tickets := []map[string]interface{}{}
+	tickets := []models.Ticket{}
 	for rows.Next() {
-		var id int
-		var ticketNumber, userIDTicket, subject, description, status, category string
-		var dikerjakanOleh, buktiMasalah, buktiSelesai *string
-		var createdAt, updatedAt string
-		var resolvedAt *string
-
-		err := rows.Scan(&id, &ticketNumber, &userIDTicket, &subject, &description,
-			&status, &category, &dikerjakanOleh, &buktiMasalah, &buktiSelesai,
-			&createdAt, &updatedAt, &resolvedAt)
-		if err != nil {
+		var t models.Ticket
+		if err := scanTicket(rows, &t); err != nil {
 			continue
 		}
+		tickets = append(tickets, t)
+	}
 
-		tickets = append(tickets, map[string]interface{}{
-			"id":              id,
-			"ticket_number":   ticketNumber,
-			"user_id":         userIDTicket,
-			"subject":         subject,
-			"description":     description,
-			"status":          status,
-			"category":        category,
-			"dikerjakan_oleh": dikerjakanOleh,
-			"bukti_masalah":   buktiMasalah,
-			"bukti_selesai":   buktiSelesai,
-			"created_at":      createdAt,
-			"updated_at":      updatedAt,
-			"resolved_at":     resolvedAt,
-		})
+	if err := services.LoadTicketFieldValues(tickets); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
 	// Calculate total pages
@@ -141,28 +159,28 @@ func GetAllTicketsAdmin(c *gin.Context) {
 		"limit":       limitNum,
 		"total_pages": totalPages,
 		"date":        dateFilter,
+		"assignee":    assignee,
+		"team":        team,
 	})
 }
 
 // GetAdminDashboardStats - Get all tickets stats (admin only)
 func GetAdminDashboardStats(c *gin.Context) {
-	userID := c.GetString("user_id")
+	var totalTickets, openTickets, inProgressTickets, resolvedTickets, closedTickets int
 
-	// Check if admin
-	var count int
-	config.DB.QueryRow(`SELECT COUNT(*) FROM helpdesk_admins WHERE user_id = ?`, userID).Scan(&count)
-	if count == 0 {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
-		return
+	// Optional team filter
+	teamFilter := "1 = 1"
+	args := []interface{}{}
+	if team := c.Query("team"); team != "" {
+		teamFilter = "team_id = ?"
+		args = append(args, team)
 	}
 
-	var totalTickets, openTickets, inProgressTickets, resolvedTickets, closedTickets int
-
-	config.DB.QueryRow(`SELECT COUNT(*) FROM helpdesk_tickets`).Scan(&totalTickets)
-	config.DB.QueryRow(`SELECT COUNT(*) FROM helpdesk_tickets WHERE status = 'baru'`).Scan(&openTickets)
-	config.DB.QueryRow(`SELECT COUNT(*) FROM helpdesk_tickets WHERE status = 'dikerjakan'`).Scan(&inProgressTickets)
-	config.DB.QueryRow(`SELECT COUNT(*) FROM helpdesk_tickets WHERE status = 'selesai'`).Scan(&resolvedTickets)
-	config.DB.QueryRow(`SELECT COUNT(*) FROM helpdesk_tickets WHERE status = 'ditutup'`).Scan(&closedTickets)
+	config.DB.QueryRow(`SELECT COUNT(*) FROM helpdesk_tickets WHERE `+teamFilter, args...).Scan(&totalTickets)
+	config.DB.QueryRow(`SELECT COUNT(*) FROM helpdesk_tickets WHERE status = 'baru' AND `+teamFilter, args...).Scan(&openTickets)
+	config.DB.QueryRow(`SELECT COUNT(*) FROM helpdesk_tickets WHERE status = 'dikerjakan' AND `+teamFilter, args...).Scan(&inProgressTickets)
+	config.DB.QueryRow(`SELECT COUNT(*) FROM helpdesk_tickets WHERE status = 'selesai' AND `+teamFilter, args...).Scan(&resolvedTickets)
+	config.DB.QueryRow(`SELECT COUNT(*) FROM helpdesk_tickets WHERE status = 'ditutup' AND `+teamFilter, args...).Scan(&closedTickets)
 
 	c.JSON(http.StatusOK, gin.H{
 		"total_tickets":       totalTickets,
@@ -175,15 +193,9 @@ func GetAdminDashboardStats(c *gin.Context) {
 
 // UpdateTicketAdmin - Update ticket status (admin only)
 func UpdateTicketAdmin(c *gin.Context) {
-	userID := c.GetString("user_id")
-	nama := c.GetString("user_nama")
 	ticketID := c.Param("id")
 
-	// Check if admin
-	var count int
-	config.DB.QueryRow(`SELECT COUNT(*) FROM helpdesk_admins WHERE user_id = ?`, userID).Scan(&count)
-	if count == 0 {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+	if _, ok := authorizeTicket(c, ticketID, services.TicketWork); !ok {
 		return
 	}
 
@@ -195,39 +207,81 @@ func UpdateTicketAdmin(c *gin.Context) {
 		return
 	}
 
-	// If changing to "selesai", check if bukti_selesai exists
-	if req.Status == "selesai" {
-		var buktiSelesai *string
-		config.DB.QueryRow(`SELECT bukti_selesai FROM helpdesk_tickets WHERE id = ?`, ticketID).Scan(&buktiSelesai)
-		if buktiSelesai == nil || *buktiSelesai == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Bukti selesai harus diupload terlebih dahulu sebelum menyelesaikan tiket"})
-			return
-		}
+	actor := currentActor(c)
+	actor.Role = services.RoleAdmin
+
+	// The status change queues its notification
+	if _, err := services.ChangeTicketStatus(ticketID, req.Status, actor); err != nil {
+		respondWorkflowError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Ticket updated"})
+}
+
+// UpdateTicketPriority - Update ticket urgency and impact (admin only)
+func UpdateTicketPriority(c *gin.Context) {
+	ticketID := c.Param("id")
+
+	var req models.UpdatePriorityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	// Build update query
-	query := "UPDATE helpdesk_tickets SET status = ?, dikerjakan_oleh = ?"
-	args := []interface{}{req.Status, nama}
+	priority, err := services.ComputePriority(req.Urgency, req.Impact)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if req.Status == "selesai" {
-		query += ", resolved_at = NOW()"
+	ticket, ok := authorizeTicket(c, ticketID, services.TicketAssign)
+	if !ok {
+		return
 	}
 
-	query += " WHERE id = ?"
-	args = append(args, ticketID)
+	tx, err := config.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
 
-	_, err := config.DB.Exec(query, args...)
+	id := ticket.ID
+	var oldPriority string
+	if err := tx.QueryRow(`SELECT priority FROM helpdesk_tickets WHERE id = ? FOR UPDATE`, id).Scan(&oldPriority); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, err = tx.Exec(`
+		UPDATE helpdesk_tickets SET urgency = ?, impact = ?, priority = ? WHERE id = ?
+	`, req.Urgency, req.Impact, priority, id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Get ticket info for notification
-	var ticketNumber, subject, oldStatus string
-	config.DB.QueryRow(`SELECT ticket_number, subject, status FROM helpdesk_tickets WHERE id = ?`, ticketID).Scan(&ticketNumber, &subject, &oldStatus)
+	if oldPriority != priority {
+		if err := services.RecordTicketEvent(tx, id, services.EventPriorityChanged, currentActor(c), oldPriority, priority); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := services.ApplySLAPolicy(tx, id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
-	// Send Telegram notification
-	go services.NotifyStatusChange(ticketNumber, subject, oldStatus, req.Status, nama)
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Ticket updated"})
+	c.JSON(http.StatusOK, gin.H{"message": "Priority updated", "priority": priority})
+}
+
+// hasPermission - Check whether the caller's role (set by middleware.LoadRole) grants a permission
+func hasPermission(c *gin.Context, permission string) bool {
+	return services.RoleHasPermission(c.GetString("user_role"), permission)
 }
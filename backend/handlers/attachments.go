@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"helpdesk-backend/config"
+	"helpdesk-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTicketAttachments - List the attachments of a ticket
+func GetTicketAttachments(c *gin.Context) {
+	ticket, ok := authorizeTicket(c, c.Param("id"), services.TicketView)
+	if !ok {
+		return
+	}
+
+	attachments, err := services.ListAttachments(ticket.ID, hasPermission(c, services.PermTicketViewAll))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, attachments)
+}
+
+// UploadTicketAttachment - Attach a file to a ticket or one of its comments
+func UploadTicketAttachment(c *gin.Context) {
+	kind := c.DefaultPostForm("kind", services.AttachmentOther)
+	if !services.IsValidAttachmentKind(kind) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrInvalidAttachmentKind.Error()})
+		return
+	}
+
+	ticket, ok := authorizeTicket(c, c.Param("id"), attachmentAction(kind))
+	if !ok {
+		return
+	}
+
+	var commentID *int
+	if raw := c.PostForm("comment_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil || !commentVisible(c, ticket.ID, id) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Comment not found"})
+			return
+		}
+		commentID = &id
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File required"})
+		return
+	}
+
+	att, err := services.SaveAttachment(ticket.ID, commentID, kind, services.FormFile(file), currentActor(c))
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, att)
+}
+
+// DeleteTicketAttachment - Delete an attachment (its uploader or staff working on the ticket)
+func DeleteTicketAttachment(c *gin.Context) {
+	ticket, ok := authorizeTicket(c, c.Param("id"), services.TicketView)
+	if !ok {
+		return
+	}
+
+	att, err := services.GetAttachment(ticket.ID, ParseInt(c.Param("attachmentId")), hasPermission(c, services.PermTicketViewAll))
+	if errors.Is(err, services.ErrAttachmentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if att.UploaderID != c.GetString("user_id") {
+		if err := services.AuthorizeTicket(ticket, c.GetString("user_id"), c.GetString("user_role"), services.TicketWork); err != nil {
+			respondWorkflowError(c, err)
+			return
+		}
+	}
+
+	if err := services.DeleteAttachment(att, currentActor(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted"})
+}
+
+// GetTicketAttachment - Download an attachment of a ticket the caller can see
+func GetTicketAttachment(c *gin.Context) {
+	ticket, ok := authorizeTicket(c, c.Param("id"), services.TicketView)
+	if !ok {
+		return
+	}
+
+	serveAttachment(c, ticket.ID, c.Param("attachmentId"), hasPermission(c, services.PermTicketViewAll), false)
+}
+
+// GetTicketAttachmentThumbnail - Download the thumbnail of an image attachment
+func GetTicketAttachmentThumbnail(c *gin.Context) {
+	ticket, ok := authorizeTicket(c, c.Param("id"), services.TicketView)
+	if !ok {
+		return
+	}
+
+	serveAttachment(c, ticket.ID, c.Param("attachmentId"), hasPermission(c, services.PermTicketViewAll), true)
+}
+
+// GetSignedAttachment - Download an attachment through a signed URL (no JWT required)
+func GetSignedAttachment(c *gin.Context) {
+	ticketID := ParseInt(c.Param("id"))
+	attachmentID := c.Param("attachmentId")
+
+	if !services.VerifyAttachmentSignature(ticketID, attachmentID, c.Query("expires"), c.Query("signature")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Link tidak valid atau sudah kedaluwarsa"})
+		return
+	}
+
+	// The signature was issued to someone allowed to see the file
+	serveAttachment(c, ticketID, attachmentID, true, false)
+}
+
+// GetSignedAttachmentThumbnail - Download an attachment thumbnail through a signed URL
+func GetSignedAttachmentThumbnail(c *gin.Context) {
+	ticketID := ParseInt(c.Param("id"))
+	attachmentID := c.Param("attachmentId")
+
+	if !services.VerifyAttachmentSignature(ticketID, attachmentID+"/thumbnail", c.Query("expires"), c.Query("signature")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Link tidak valid atau sudah kedaluwarsa"})
+		return
+	}
+
+	serveAttachment(c, ticketID, attachmentID, true, true)
+}
+
+// serveAttachment - Send an attachment (or its thumbnail) by ID, or the current bukti file
+// for "masalah"/"selesai"
+func serveAttachment(c *gin.Context, ticketID int, attachmentID string, includeInternal, thumbnail bool) {
+	var storedPath, name, mimeType string
+
+	if column, ok := services.BuktiColumns[attachmentID]; ok {
+		if thumbnail {
+			column = services.BuktiThumbColumns[attachmentID]
+		}
+		var path *string
+		err := config.DB.QueryRow(`SELECT `+column+` FROM helpdesk_tickets WHERE id = ?`, ticketID).Scan(&path)
+		if err == sql.ErrNoRows || (err == nil && (path == nil || *path == "")) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		storedPath, name = *path, filepath.Base(*path)
+		mimeType = mime.TypeByExtension(filepath.Ext(name))
+	} else {
+		att, err := services.GetAttachment(ticketID, ParseInt(attachmentID), includeInternal)
+		if errors.Is(err, services.ErrAttachmentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if att.ScanStatus == services.ScanRejected {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+			return
+		}
+		storedPath, name, mimeType = att.StoredPath, att.OriginalName, att.MimeType
+		if thumbnail {
+			if att.ThumbnailPath == nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+				return
+			}
+			storedPath, mimeType = *att.ThumbnailPath, "image/jpeg"
+			name = strings.TrimSuffix(name, filepath.Ext(name)) + "_thumb.jpg"
+		}
+	}
+
+	file, size, err := services.FileStorage.Open(storedPath)
+	if errors.Is(err, services.ErrStoredFileNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	c.DataFromReader(http.StatusOK, size, mimeType, file, map[string]string{
+		"Content-Disposition":     mime.FormatMediaType("inline", map[string]string{"filename": name}),
+		"Cache-Control":           "private, max-age=300",
+		"X-Content-Type-Options":  "nosniff",
+		"Content-Security-Policy": "sandbox",
+	})
+}
+
+// attachmentAction - Ticket action required to add an attachment of a kind.
+// Bukti kinds follow the rules of their dedicated endpoints.
+func attachmentAction(kind string) string {
+	switch kind {
+	case services.AttachmentMasalah:
+		return services.TicketRequest
+	case services.AttachmentSelesai:
+		return services.TicketWork
+	}
+	return services.TicketView
+}
+
+// respondUploadError - Map upload validation errors to HTTP responses
+func respondUploadError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrFileEmpty), errors.Is(err, services.ErrInvalidAttachmentKind),
+		errors.Is(err, services.ErrUploadInvalidChecksum):
+		status = http.StatusBadRequest
+	case errors.Is(err, services.ErrUploadNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrUploadInProgress):
+		status = http.StatusConflict
+	case errors.Is(err, services.ErrUploadChecksum), errors.Is(err, services.ErrFileInfected):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrScanUnavailable):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, services.ErrFileTooLarge), errors.Is(err, services.ErrUploadChunkTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrFileTypeNotAllowed), errors.Is(err, services.ErrFileExtensionMismatch),
+		errors.Is(err, services.ErrImageInvalid):
+		status = http.StatusUnsupportedMediaType
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+// commentVisible - Check that a comment belongs to the ticket and the caller may see it
+func commentVisible(c *gin.Context, ticketID, commentID int) bool {
+	var isInternal bool
+	err := config.DB.QueryRow(`
+		SELECT is_internal FROM helpdesk_ticket_comments WHERE id = ? AND ticket_id = ?
+	`, commentID, ticketID).Scan(&isInternal)
+	return err == nil && (!isInternal || hasPermission(c, services.PermTicketViewAll))
+}
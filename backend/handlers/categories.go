@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"database/sql"
 	"net/http"
 
 	"helpdesk-backend/config"
@@ -9,26 +10,348 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// GetCategories - Get all ticket categories
+// GetCategories - Get active ticket categories as a tree
 func GetCategories(c *gin.Context) {
-	rows, err := config.DB.Query(`
-		SELECT id, name, description FROM helpdesk_categories
-	`)
+	categories, err := listCategories(true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, buildCategoryTree(categories, nil))
+}
+
+// GetAllCategoriesAdmin - Get all categories including inactive ones (admin only)
+func GetAllCategoriesAdmin(c *gin.Context) {
+	categories, err := listCategories(false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, categories)
+}
+
+// CreateCategory - Create a ticket category (admin only)
+func CreateCategory(c *gin.Context) {
+	var req models.CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if categoryNameTaken(req.Name, req.ParentID, 0) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Nama kategori sudah digunakan"})
+		return
+	}
+	if req.ParentID != nil {
+		if _, err := getCategory(*req.ParentID); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Kategori induk tidak ditemukan"})
+			return
+		}
+	}
+	if req.TeamID != nil && !teamExists(*req.TeamID) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Team not found"})
+		return
+	}
+
+	// New categories go to the end of the list unless an order is given
+	sortOrder := 0
+	if req.SortOrder != nil {
+		sortOrder = *req.SortOrder
+	} else {
+		config.DB.QueryRow(`SELECT COALESCE(MAX(sort_order), 0) + 1 FROM helpdesk_categories`).Scan(&sortOrder)
+	}
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	result, err := config.DB.Exec(`
+		INSERT INTO helpdesk_categories (parent_id, name, description, team_id, sort_order, is_active)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, req.ParentID, req.Name, req.Description, req.TeamID, sortOrder, isActive)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	cat, err := getCategory(int(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, cat)
+}
+
+// UpdateCategory - Update a ticket category (admin only)
+func UpdateCategory(c *gin.Context) {
+	categoryID := ParseInt(c.Param("id"))
+
+	var req models.CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cat, err := getCategory(categoryID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if categoryNameTaken(req.Name, req.ParentID, categoryID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Nama kategori sudah digunakan"})
+		return
+	}
+	if req.ParentID != nil {
+		if _, err := getCategory(*req.ParentID); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Kategori induk tidak ditemukan"})
+			return
+		}
+		if isCategoryDescendant(*req.ParentID, categoryID) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Kategori tidak boleh menjadi induk dari dirinya sendiri"})
+			return
+		}
+	}
+	if req.TeamID != nil && !teamExists(*req.TeamID) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Team not found"})
+		return
+	}
+
+	sortOrder := cat.SortOrder
+	if req.SortOrder != nil {
+		sortOrder = *req.SortOrder
+	}
+	isActive := cat.IsActive
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE helpdesk_categories SET parent_id = ?, name = ?, description = ?, team_id = ?, sort_order = ?, is_active = ?
+		WHERE id = ?
+	`, req.ParentID, req.Name, req.Description, req.TeamID, sortOrder, isActive, categoryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Tickets and rules keep the category name as a label next to its ID
+	if req.Name != cat.Name {
+		for _, query := range []string{
+			`UPDATE helpdesk_tickets SET category = ? WHERE category_id = ?`,
+			`UPDATE helpdesk_sla_policies SET category = ? WHERE category_id = ?`,
+			`UPDATE helpdesk_routing_rules SET category = ? WHERE category_id = ?`,
+			`UPDATE helpdesk_staff_skills SET category = ? WHERE category_id = ?`,
+		} {
+			if _, err := tx.Exec(query, req.Name, categoryID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
+	cat, err = getCategory(categoryID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+
+	c.JSON(http.StatusOK, cat)
+}
+
+// DeleteCategory - Deactivate a ticket category; existing tickets keep it (admin only)
+func DeleteCategory(c *gin.Context) {
+	if _, err := getCategory(ParseInt(c.Param("id"))); err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	_, err := config.DB.Exec(`UPDATE helpdesk_categories SET is_active = 0 WHERE id = ?`, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Category deactivated"})
+}
+
+// ReorderCategories - Set the display order of categories (admin only)
+func ReorderCategories(c *gin.Context) {
+	var req models.CategoryOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	for i, id := range req.IDs {
+		if _, err := tx.Exec(`UPDATE helpdesk_categories SET sort_order = ? WHERE id = ?`, i+1, id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Category order updated"})
+}
+
+// categoryColumns - Columns selected for models.Category, in the order read by scanCategory
+const categoryColumns = `id, parent_id, name, description, team_id, sort_order, is_active`
+
+func scanCategory(row rowScanner, cat *models.Category) error {
+	return row.Scan(&cat.ID, &cat.ParentID, &cat.Name, &cat.Description, &cat.TeamID, &cat.SortOrder, &cat.IsActive)
+}
+
+// buildCategoryTree - Nest categories under their parents, keeping the list order.
+// Categories whose parent is not in the list (e.g. inactive) are left out.
+func buildCategoryTree(categories []models.Category, parentID *int) []models.Category {
+	tree := []models.Category{}
+	for _, cat := range categories {
+		if (parentID == nil && cat.ParentID == nil) || (parentID != nil && cat.ParentID != nil && *cat.ParentID == *parentID) {
+			id := cat.ID
+			cat.Children = buildCategoryTree(categories, &id)
+			tree = append(tree, cat)
+		}
+	}
+	return tree
+}
+
+// isCategoryDescendant - Check whether a category is the same as or below another one
+func isCategoryDescendant(categoryID, ancestorID int) bool {
+	seen := map[int]bool{}
+	current := &categoryID
+	for current != nil && !seen[*current] {
+		if *current == ancestorID {
+			return true
+		}
+		seen[*current] = true
+
+		var parentID *int
+		config.DB.QueryRow(`SELECT parent_id FROM helpdesk_categories WHERE id = ?`, *current).Scan(&parentID)
+		current = parentID
+	}
+	return false
+}
+
+func listCategories(onlyActive bool) ([]models.Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM helpdesk_categories`
+	if onlyActive {
+		query += " WHERE is_active = 1"
+	}
+	query += " ORDER BY sort_order, name"
+
+	rows, err := config.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
 	categories := []models.Category{}
 	for rows.Next() {
 		var cat models.Category
-		if err := rows.Scan(&cat.ID, &cat.Name, &cat.Description); err != nil {
+		if err := scanCategory(rows, &cat); err != nil {
 			continue
 		}
 		categories = append(categories, cat)
 	}
+	return categories, nil
+}
 
-	c.JSON(http.StatusOK, categories)
+func getCategory(id int) (models.Category, error) {
+	var cat models.Category
+	err := scanCategory(config.DB.QueryRow(`
+		SELECT `+categoryColumns+` FROM helpdesk_categories WHERE id = ?
+	`, id), &cat)
+	return cat, err
+}
+
+// categoryNameTaken - Check whether a sibling category already uses a name
+func categoryNameTaken(name string, parentID *int, exceptID int) bool {
+	var count int
+	config.DB.QueryRow(`
+		SELECT COUNT(*) FROM helpdesk_categories WHERE name = ? AND parent_id <=> ? AND id <> ?
+	`, name, parentID, exceptID).Scan(&count)
+	return count > 0
+}
+
+// categoryPathActive - Check that a category and all of its ancestors are active
+func categoryPathActive(categoryID int) bool {
+	seen := map[int]bool{}
+	current := &categoryID
+	for current != nil && !seen[*current] {
+		seen[*current] = true
+
+		var isActive bool
+		var parentID *int
+		err := config.DB.QueryRow(`
+			SELECT is_active, parent_id FROM helpdesk_categories WHERE id = ?
+		`, *current).Scan(&isActive, &parentID)
+		if err != nil || !isActive {
+			return false
+		}
+		current = parentID
+	}
+	return true
+}
+
+// resolveTicketCategory - Find the category of a new ticket by ID, or by name when
+// only one active category has it. It must be active under active parents and a leaf,
+// i.e. have no active subcategories.
+func resolveTicketCategory(categoryID *int, name string) (models.Category, bool) {
+	var cat models.Category
+	var err error
+	if categoryID != nil {
+		cat, err = getCategory(*categoryID)
+	} else {
+		var matches int
+		config.DB.QueryRow(`
+			SELECT COUNT(*) FROM helpdesk_categories WHERE name = ? AND is_active = 1
+		`, name).Scan(&matches)
+		if matches != 1 {
+			return cat, false
+		}
+		err = scanCategory(config.DB.QueryRow(`
+			SELECT `+categoryColumns+` FROM helpdesk_categories WHERE name = ? AND is_active = 1
+		`, name), &cat)
+	}
+	if err != nil || !categoryPathActive(cat.ID) {
+		return cat, false
+	}
+
+	var children int
+	config.DB.QueryRow(`
+		SELECT COUNT(*) FROM helpdesk_categories WHERE parent_id = ? AND is_active = 1
+	`, cat.ID).Scan(&children)
+	return cat, children == 0
 }
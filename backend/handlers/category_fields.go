@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"helpdesk-backend/config"
+	"helpdesk-backend/models"
+	"helpdesk-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-sql-driver/mysql"
+)
+
+// GetCategoryFields - Get active custom fields for a category, including inherited ones
+func GetCategoryFields(c *gin.Context) {
+	fields, err := services.CategoryFields(ParseInt(c.Param("id")), true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, fields)
+}
+
+// GetCategoryFieldsAdmin - Get all custom fields for a category including inactive ones (admin only)
+func GetCategoryFieldsAdmin(c *gin.Context) {
+	fields, err := services.CategoryFields(ParseInt(c.Param("id")), false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, fields)
+}
+
+// CreateCategoryField - Define a custom field on a category (admin only)
+func CreateCategoryField(c *gin.Context) {
+	categoryID := ParseInt(c.Param("id"))
+
+	var req models.CategoryFieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := services.ValidateFieldDefinition(req); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := getCategory(categoryID); err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	result, err := config.DB.Exec(`
+		INSERT INTO helpdesk_category_fields (category_id, field_key, label, field_type, options, is_required, sort_order, is_active)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, categoryID, req.Key, req.Label, req.Type, services.EncodeFieldOptions(req.Options), req.IsRequired, req.SortOrder, isActive)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Key field sudah digunakan pada kategori ini"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	field, err := services.GetCategoryField(int(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, field)
+}
+
+// UpdateCategoryField - Update a custom field definition (admin only)
+func UpdateCategoryField(c *gin.Context) {
+	fieldID := ParseInt(c.Param("fieldId"))
+
+	var req models.CategoryFieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := services.ValidateFieldDefinition(req); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	field, err := services.GetCategoryField(fieldID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Field not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	isActive := field.IsActive
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	_, err = config.DB.Exec(`
+		UPDATE helpdesk_category_fields
+		SET field_key = ?, label = ?, field_type = ?, options = ?, is_required = ?, sort_order = ?, is_active = ?
+		WHERE id = ?
+	`, req.Key, req.Label, req.Type, services.EncodeFieldOptions(req.Options), req.IsRequired, req.SortOrder, isActive, fieldID)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Key field sudah digunakan pada kategori ini"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	field, err = services.GetCategoryField(fieldID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, field)
+}
+
+// DeleteCategoryField - Deactivate a custom field; stored values are kept (admin only)
+func DeleteCategoryField(c *gin.Context) {
+	if _, err := services.GetCategoryField(ParseInt(c.Param("fieldId"))); err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Field not found"})
+		return
+	}
+
+	_, err := config.DB.Exec(`UPDATE helpdesk_category_fields SET is_active = 0 WHERE id = ?`, c.Param("fieldId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Field deactivated"})
+}
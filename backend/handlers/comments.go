@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"helpdesk-backend/config"
+	"helpdesk-backend/models"
+	"helpdesk-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetComments - Get comment thread of a ticket
+func GetComments(c *gin.Context) {
+	ticketID := c.Param("id")
+
+	admin := hasPermission(c, services.PermTicketViewAll)
+	if _, ok := authorizeTicket(c, ticketID, services.TicketView); !ok {
+		return
+	}
+
+	query := `
+		SELECT id, ticket_id, user_id, user_nama, body, is_internal, created_at, updated_at
+		FROM helpdesk_ticket_comments
+		WHERE ticket_id = ?`
+	// Internal notes are only visible to admins
+	if !admin {
+		query += " AND is_internal = 0"
+	}
+	query += " ORDER BY created_at ASC, id ASC"
+
+	rows, err := config.DB.Query(query, ticketID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	comments := []models.Comment{}
+	for rows.Next() {
+		var cm models.Comment
+		if err := rows.Scan(&cm.ID, &cm.TicketID, &cm.UserID, &cm.UserNama, &cm.Body,
+			&cm.IsInternal, &cm.CreatedAt, &cm.UpdatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		comments = append(comments, cm)
+	}
+
+	c.JSON(http.StatusOK, comments)
+}
+
+// CreateComment - Add a reply or internal note to a ticket
+func CreateComment(c *gin.Context) {
+	userID := c.GetString("user_id")
+	nama := c.GetString("user_nama")
+	ticketID := c.Param("id")
+
+	var req models.CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	admin := hasPermission(c, services.PermTicketViewAll)
+	ticket, ok := authorizeTicket(c, ticketID, services.TicketView)
+	if !ok {
+		return
+	}
+
+	if req.IsInternal && !admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Hanya admin yang dapat membuat catatan internal"})
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO helpdesk_ticket_comments (ticket_id, user_id, user_nama, body, is_internal)
+		VALUES (?, ?, ?, ?, ?)
+	`, ticketID, userID, nama, req.Body, req.IsInternal)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	if err := services.NotifyNewComment(tx, ticket.ID, ticket.TicketNumber, ticket.Subject, nama, req.Body, req.IsInternal); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !req.IsInternal && ticket.OwnerID != userID {
+		if err := services.NotifyRequesterComment(tx, ticket.ID, ticket.TicketNumber, ticket.Subject, ticket.OwnerID, nama, req.Body); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	cm, err := getComment(int(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// A public admin reply counts as the first response
+	if admin && !req.IsInternal {
+		services.MarkFirstResponse(ticket.ID)
+	}
+
+	c.JSON(http.StatusCreated, cm)
+}
+
+// UpdateComment - Edit own comment
+func UpdateComment(c *gin.Context) {
+	userID := c.GetString("user_id")
+	ticketID := c.Param("id")
+	commentID := c.Param("commentId")
+
+	var req models.UpdateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	admin := hasPermission(c, services.PermTicketViewAll)
+	if _, ok := authorizeTicket(c, ticketID, services.TicketView); !ok {
+		return
+	}
+
+	var authorID string
+	var isInternal bool
+	err := config.DB.QueryRow(`
+		SELECT user_id, is_internal FROM helpdesk_ticket_comments WHERE id = ? AND ticket_id = ?
+	`, commentID, ticketID).Scan(&authorID, &isInternal)
+	if err == sql.ErrNoRows || (err == nil && isInternal && !admin) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if authorID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Hanya penulis yang dapat mengubah komentar"})
+		return
+	}
+
+	if req.IsInternal != nil {
+		if *req.IsInternal && !admin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Hanya admin yang dapat membuat catatan internal"})
+			return
+		}
+		isInternal = *req.IsInternal
+	}
+
+	_, err = config.DB.Exec(`
+		UPDATE helpdesk_ticket_comments SET body = ?, is_internal = ? WHERE id = ?
+	`, req.Body, isInternal, commentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	cm, err := getComment(ParseInt(commentID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, cm)
+}
+
+func getComment(id int) (models.Comment, error) {
+	var cm models.Comment
+	err := config.DB.QueryRow(`
+		SELECT id, ticket_id, user_id, user_nama, body, is_internal, created_at, updated_at
+		FROM helpdesk_ticket_comments WHERE id = ?
+	`, id).Scan(&cm.ID, &cm.TicketID, &cm.UserID, &cm.UserNama, &cm.Body,
+		&cm.IsInternal, &cm.CreatedAt, &cm.UpdatedAt)
+	return cm, err
+}
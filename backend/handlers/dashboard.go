@@ -5,6 +5,7 @@ import (
 
 	"helpdesk-backend/config"
 	"helpdesk-backend/models"
+	"helpdesk-backend/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -48,8 +49,7 @@ func GetRecentTickets(c *gin.Context) {
 	userID := c.GetString("user_id")
 
 	rows, err := config.DB.Query(`
-		SELECT id, ticket_number, user_id, subject, description, status, category, 
-		       dikerjakan_oleh, bukti_masalah, bukti_selesai, created_at, updated_at, resolved_at
+		SELECT `+ticketColumns+`
 		FROM helpdesk_tickets 
 		WHERE user_id = ?
 		ORDER BY created_at DESC
@@ -65,14 +65,17 @@ func GetRecentTickets(c *gin.Context) {
 	tickets := []models.Ticket{}
 	for rows.Next() {
 		var t models.Ticket
-		err := rows.Scan(&t.ID, &t.TicketNumber, &t.UserID, &t.Subject, &t.Description,
-			&t.Status, &t.Category, &t.DikerjakanOleh, &t.BuktiMasalah, &t.BuktiSelesai,
-			&t.CreatedAt, &t.UpdatedAt, &t.ResolvedAt)
+		err := scanTicket(rows, &t)
 		if err != nil {
 			continue
 		}
 		tickets = append(tickets, t)
 	}
 
+	if err := services.LoadTicketFieldValues(tickets); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, tickets)
 }
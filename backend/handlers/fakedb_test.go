@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// fakeDB is a database/sql driver answering the ticket lookup done by authorizeTicket
+// (services.LoadTicketRef). Any other query fails, so a test notices when a handler
+// goes past authorization.
+type fakeDB struct {
+	tickets map[string][]driver.Value // ticket ID → id, ticket_number, subject, status, user_id, assignee_id
+}
+
+var fakeTickets = &fakeDB{tickets: map[string][]driver.Value{}}
+
+func init() {
+	sql.Register("helpdesk-fake", fakeTickets)
+}
+
+func (d *fakeDB) Open(string) (driver.Conn, error) { return fakeConn{d}, nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.db, query}, nil }
+func (fakeConn) Close() error                                { return nil }
+func (fakeConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("fake db: transactions not supported")
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("fake db: unexpected exec: %s", s.query)
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if !strings.Contains(s.query, "SELECT id, ticket_number, subject, status, user_id, assignee_id") {
+		return nil, fmt.Errorf("fake db: unexpected query: %s", s.query)
+	}
+	rows := &fakeRows{}
+	if row, ok := s.db.tickets[fmt.Sprint(args[0])]; ok {
+		rows.rows = append(rows.rows, row)
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (*fakeRows) Columns() []string {
+	return []string{"id", "ticket_number", "subject", "status", "user_id", "assignee_id"}
+}
+func (*fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
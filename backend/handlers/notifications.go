@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"helpdesk-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNotificationOutbox - List outbox deliveries by status (default: failed)
+func GetNotificationOutbox(c *gin.Context) {
+	status := c.DefaultQuery("status", services.DeliveryFailed)
+	if status != services.DeliveryPending && status != services.DeliverySent && status != services.DeliveryFailed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Status tidak valid"})
+		return
+	}
+	limit := ParseInt(c.DefaultQuery("limit", "100"))
+	if limit < 1 || limit > 500 {
+		limit = 100
+	}
+
+	deliveries, err := services.ListDeliveries(status, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// RetryNotificationDelivery - Queue a failed delivery again
+func RetryNotificationDelivery(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": services.ErrDeliveryNotFound.Error()})
+		return
+	}
+
+	err = services.RetryDelivery(id)
+	switch {
+	case errors.Is(err, services.ErrDeliveryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrDeliveryNotFailed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification queued for retry"})
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+
+	"helpdesk-backend/config"
+	"helpdesk-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCategoryReport - Ticket counts per category, rolled up to parent categories (admin only)
+func GetCategoryReport(c *gin.Context) {
+	// Optional date range on ticket creation
+	dateFilter := ""
+	args := []interface{}{}
+	if from := c.Query("from"); from != "" {
+		dateFilter += " AND DATE(t.created_at) >= ?"
+		args = append(args, from)
+	}
+	if to := c.Query("to"); to != "" {
+		dateFilter += " AND DATE(t.created_at) <= ?"
+		args = append(args, to)
+	}
+
+	categories, err := listCategories(false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT t.category_id, COUNT(*) FROM helpdesk_tickets t
+		WHERE t.category_id IS NOT NULL`+dateFilter+`
+		GROUP BY t.category_id
+	`, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	counts := map[int]int{}
+	for rows.Next() {
+		var categoryID, count int
+		if err := rows.Scan(&categoryID, &count); err != nil {
+			continue
+		}
+		counts[categoryID] = count
+	}
+
+	c.JSON(http.StatusOK, buildCategoryReport(buildCategoryTree(categories, nil), counts))
+}
+
+// buildCategoryReport - Attach ticket counts to a category tree, summing children into their parents
+func buildCategoryReport(tree []models.Category, counts map[int]int) []models.CategoryReport {
+	report := []models.CategoryReport{}
+	for _, cat := range tree {
+		r := models.CategoryReport{
+			ID:          cat.ID,
+			Name:        cat.Name,
+			TicketCount: counts[cat.ID],
+			Children:    buildCategoryReport(cat.Children, counts),
+		}
+		r.TotalCount = r.TicketCount
+		for _, child := range r.Children {
+			r.TotalCount += child.TotalCount
+		}
+		report = append(report, r)
+	}
+	return report
+}
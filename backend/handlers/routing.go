@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"helpdesk-backend/config"
+	"helpdesk-backend/models"
+	"helpdesk-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetRoutingRules - Get auto-assignment rules per category (admin only)
+func GetRoutingRules(c *gin.Context) {
+	rows, err := config.DB.Query(`
+		SELECT category_id, category, strategy, last_assignee_id, is_active, updated_at
+		FROM helpdesk_routing_rules ORDER BY category, category_id
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	rules := []models.RoutingRule{}
+	for rows.Next() {
+		var r models.RoutingRule
+		if err := rows.Scan(&r.CategoryID, &r.Category, &r.Strategy, &r.LastAssigneeID, &r.IsActive, &r.UpdatedAt); err != nil {
+			continue
+		}
+		rules = append(rules, r)
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// SaveRoutingRule - Create or update the auto-assignment rule of a category (admin only)
+func SaveRoutingRule(c *gin.Context) {
+	var req models.RoutingRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !services.IsValidStrategy(req.Strategy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrUnknownStrategy.Error()})
+		return
+	}
+
+	category, err := services.CategoryName(req.CategoryID)
+	if errors.Is(err, services.ErrCategoryNotFound) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	_, err = config.DB.Exec(`
+		INSERT INTO helpdesk_routing_rules (category_id, category, strategy, is_active) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE category = VALUES(category), strategy = VALUES(strategy), is_active = VALUES(is_active)
+	`, req.CategoryID, category, req.Strategy, isActive)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Routing rule saved"})
+}
+
+// DeleteRoutingRule - Remove the auto-assignment rule of a category (admin only)
+func DeleteRoutingRule(c *gin.Context) {
+	result, err := config.DB.Exec(`DELETE FROM helpdesk_routing_rules WHERE category_id = ?`, c.Param("categoryId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Routing rule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Routing rule deleted"})
+}
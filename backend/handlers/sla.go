@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"helpdesk-backend/config"
+	"helpdesk-backend/models"
+	"helpdesk-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSLAPolicies - Get all SLA policies (admin only)
+func GetSLAPolicies(c *gin.Context) {
+	rows, err := config.DB.Query(`
+		SELECT id, category_id, category, priority, response_minutes, resolution_minutes, is_active, created_at, updated_at
+		FROM helpdesk_sla_policies
+		ORDER BY category IS NULL, category, priority IS NULL, priority
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	policies := []models.SLAPolicy{}
+	for rows.Next() {
+		var p models.SLAPolicy
+		if err := rows.Scan(&p.ID, &p.CategoryID, &p.Category, &p.Priority, &p.ResponseMinutes,
+			&p.ResolutionMinutes, &p.IsActive, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			continue
+		}
+		policies = append(policies, p)
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// CreateSLAPolicy - Create an SLA policy (admin only)
+func CreateSLAPolicy(c *gin.Context) {
+	var req models.SLAPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ResolutionMinutes < req.ResponseMinutes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Target penyelesaian tidak boleh lebih cepat dari target respon"})
+		return
+	}
+
+	category, ok := slaPolicyCategory(c, req.CategoryID)
+	if !ok {
+		return
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	result, err := config.DB.Exec(`
+		INSERT INTO helpdesk_sla_policies (category_id, category, priority, response_minutes, resolution_minutes, is_active)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, req.CategoryID, category, emptyToNil(req.Priority), req.ResponseMinutes, req.ResolutionMinutes, isActive)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	p, err := getSLAPolicy(int(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, p)
+}
+
+// UpdateSLAPolicy - Update an SLA policy (admin only)
+func UpdateSLAPolicy(c *gin.Context) {
+	policyID := ParseInt(c.Param("id"))
+
+	var req models.SLAPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ResolutionMinutes < req.ResponseMinutes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Target penyelesaian tidak boleh lebih cepat dari target respon"})
+		return
+	}
+
+	p, err := getSLAPolicy(policyID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "SLA policy not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, ok := slaPolicyCategory(c, req.CategoryID)
+	if !ok {
+		return
+	}
+
+	isActive := p.IsActive
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	_, err = config.DB.Exec(`
+		UPDATE helpdesk_sla_policies
+		SET category_id = ?, category = ?, priority = ?, response_minutes = ?, resolution_minutes = ?, is_active = ?
+		WHERE id = ?
+	`, req.CategoryID, category, emptyToNil(req.Priority), req.ResponseMinutes, req.ResolutionMinutes, isActive, policyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	p, err = getSLAPolicy(policyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+// DeleteSLAPolicy - Delete an SLA policy (admin only)
+func DeleteSLAPolicy(c *gin.Context) {
+	result, err := config.DB.Exec(`DELETE FROM helpdesk_sla_policies WHERE id = ?`, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "SLA policy not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "SLA policy deleted"})
+}
+
+func getSLAPolicy(id int) (models.SLAPolicy, error) {
+	var p models.SLAPolicy
+	err := config.DB.QueryRow(`
+		SELECT id, category_id, category, priority, response_minutes, resolution_minutes, is_active, created_at, updated_at
+		FROM helpdesk_sla_policies WHERE id = ?
+	`, id).Scan(&p.ID, &p.CategoryID, &p.Category, &p.Priority, &p.ResponseMinutes,
+		&p.ResolutionMinutes, &p.IsActive, &p.CreatedAt, &p.UpdatedAt)
+	return p, err
+}
+
+// slaPolicyCategory - Look up the name of a policy's category; no category means any category
+func slaPolicyCategory(c *gin.Context, categoryID *int) (*string, bool) {
+	if categoryID == nil {
+		return nil, true
+	}
+	name, err := services.CategoryName(*categoryID)
+	if errors.Is(err, services.ErrCategoryNotFound) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return &name, true
+}
+
+// emptyToNil - Treat an empty optional string as NULL
+func emptyToNil(s *string) *string {
+	if s == nil || *s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"helpdesk-backend/models"
+	"helpdesk-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetStaff - Get helpdesk staff with availability and skills (admin only)
+func GetStaff(c *gin.Context) {
+	staff, err := services.ListStaff(false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, staff)
+}
+
+// UpdateStaffAvailability - Mark a staff member as available or unavailable for routing (admin only)
+func UpdateStaffAvailability(c *gin.Context) {
+	var req models.StaffAvailabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := services.SetStaffAvailability(c.Param("userId"), req.IsAvailable)
+	if errors.Is(err, services.ErrStaffNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Availability updated"})
+}
+
+// UpdateStaffSkills - Set the categories a staff member handles (admin only)
+func UpdateStaffSkills(c *gin.Context) {
+	var req models.StaffSkillsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := services.SetStaffSkills(c.Param("userId"), req.CategoryIDs)
+	if errors.Is(err, services.ErrStaffNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrCategoryNotFound) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Skills updated"})
+}
+
+// GrantStaff - Give an employee helpdesk staff access (admin only)
+func GrantStaff(c *gin.Context) {
+	var req models.StaffGrantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	staff, err := services.GrantStaff(req.UserID, req.Role, currentActor(c))
+	switch {
+	case errors.Is(err, services.ErrPegawaiNotFound), errors.Is(err, services.ErrInvalidRole):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrAlreadyStaff):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, staff)
+}
+
+// RevokeStaff - Remove helpdesk staff access from a user (admin only)
+func RevokeStaff(c *gin.Context) {
+	err := services.RevokeStaff(c.Param("userId"), currentActor(c))
+	switch {
+	case errors.Is(err, services.ErrStaffNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrRevokeSelf):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Staff access revoked"})
+}
+
+// UpdateStaffRole - Change the role of a staff member (admin only)
+func UpdateStaffRole(c *gin.Context) {
+	var req models.StaffRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := services.SetStaffRole(c.Param("userId"), req.Role, currentActor(c))
+	switch {
+	case errors.Is(err, services.ErrStaffNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrInvalidRole), errors.Is(err, services.ErrChangeOwnRole):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated"})
+}
+
+// GetStaffAudit - Get the history of staff access changes (admin only)
+func GetStaffAudit(c *gin.Context) {
+	limit := ParseInt(c.DefaultQuery("limit", "100"))
+	if limit < 1 || limit > 500 {
+		limit = 100
+	}
+
+	entries, err := services.ListAdminAudit(c.Query("user_id"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
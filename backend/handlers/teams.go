@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"net/http"
+
+	"helpdesk-backend/config"
+	"helpdesk-backend/models"
+	"helpdesk-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTeams - Get support teams with their members (admin only)
+func GetTeams(c *gin.Context) {
+	rows, err := config.DB.Query(`
+		SELECT id, name, description, telegram_chat_id, created_at FROM helpdesk_teams ORDER BY name
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	teams := []models.Team{}
+	for rows.Next() {
+		var t models.Team
+		if err := rows.Scan(&t.ID, &t.Name, &t.Description, &t.TelegramChatID, &t.CreatedAt); err != nil {
+			continue
+		}
+		teams = append(teams, t)
+	}
+
+	for i := range teams {
+		members, err := services.TeamMemberIDs(teams[i].ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		teams[i].Members = members
+	}
+
+	c.JSON(http.StatusOK, teams)
+}
+
+// CreateTeam - Create a support team (admin only)
+func CreateTeam(c *gin.Context) {
+	var req models.TeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := config.DB.Exec(`
+		INSERT INTO helpdesk_teams (name, description, telegram_chat_id) VALUES (?, ?, ?)
+	`, req.Name, req.Description, emptyToNil(req.TelegramChatID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Team created", "id": id})
+}
+
+// UpdateTeam - Update a support team (admin only)
+func UpdateTeam(c *gin.Context) {
+	var req models.TeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !teamExists(c.Param("id")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	_, err := config.DB.Exec(`
+		UPDATE helpdesk_teams SET name = ?, description = ?, telegram_chat_id = ? WHERE id = ?
+	`, req.Name, req.Description, emptyToNil(req.TelegramChatID), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team updated"})
+}
+
+// DeleteTeam - Delete a support team; its categories and tickets become unassigned (admin only)
+func DeleteTeam(c *gin.Context) {
+	teamID := c.Param("id")
+	if !teamExists(teamID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		`UPDATE helpdesk_categories SET team_id = NULL WHERE team_id = ?`,
+		`UPDATE helpdesk_tickets SET team_id = NULL WHERE team_id = ?`,
+		`DELETE FROM helpdesk_team_members WHERE team_id = ?`,
+		`DELETE FROM helpdesk_teams WHERE id = ?`,
+	} {
+		if _, err := tx.Exec(query, teamID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team deleted"})
+}
+
+// UpdateTeamMembers - Replace the members of a team (admin only)
+func UpdateTeamMembers(c *gin.Context) {
+	teamID := c.Param("id")
+
+	var req models.TeamMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !teamExists(teamID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	// Members must be helpdesk staff
+	for _, userID := range req.UserIDs {
+		if _, err := services.FindStaff(userID); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error() + ": " + userID})
+			return
+		}
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM helpdesk_team_members WHERE team_id = ?`, teamID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, userID := range req.UserIDs {
+		if _, err := tx.Exec(`
+			INSERT IGNORE INTO helpdesk_team_members (team_id, user_id) VALUES (?, ?)
+		`, teamID, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team members updated"})
+}
+
+// UpdateCategoryTeam - Map a category to its default team (admin only)
+func UpdateCategoryTeam(c *gin.Context) {
+	var req models.CategoryTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.TeamID != nil && !teamExists(*req.TeamID) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Team not found"})
+		return
+	}
+
+	var count int
+	config.DB.QueryRow(`SELECT COUNT(*) FROM helpdesk_categories WHERE id = ?`, c.Param("id")).Scan(&count)
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	_, err := config.DB.Exec(`UPDATE helpdesk_categories SET team_id = ? WHERE id = ?`, req.TeamID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Category team updated"})
+}
+
+func teamExists(teamID interface{}) bool {
+	var count int
+	config.DB.QueryRow(`SELECT COUNT(*) FROM helpdesk_teams WHERE id = ?`, teamID).Scan(&count)
+	return count > 0
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+
+	"helpdesk-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTelegramLink - Get the Telegram account linked to the current user
+func GetTelegramLink(c *gin.Context) {
+	link, err := services.GetTelegramLink(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+// CreateTelegramLink - Generate a bot deep link that links the current user's Telegram chat
+func CreateTelegramLink(c *gin.Context) {
+	code, err := services.CreateTelegramLinkCode(c.GetString("user_id"))
+	if errors.Is(err, services.ErrTelegramNotConfigured) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, code)
+}
+
+// DeleteTelegramLink - Unlink the current user's Telegram account
+func DeleteTelegramLink(c *gin.Context) {
+	if err := services.UnlinkTelegram(c.GetString("user_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Telegram unlinked"})
+}
+
+// TelegramWebhook - Receive bot updates from Telegram (TELEGRAM_BOT_MODE=webhook)
+func TelegramWebhook(c *gin.Context) {
+	secret := services.TelegramWebhookSecret()
+	token := c.GetHeader("X-Telegram-Bot-Api-Secret-Token")
+	if secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var update services.TelegramUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Telegram redelivers updates that are not acknowledged, so failures are only logged
+	if err := services.HandleTelegramUpdate(update); err != nil {
+		log.Println("Telegram update failed:", err)
+	}
+	c.Status(http.StatusOK)
+}
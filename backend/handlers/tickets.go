@@ -1,10 +1,11 @@
 package handlers
 
 import (
-	"database/sql"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
-	"os"
 	"strconv"
 	"time"
 
@@ -20,8 +21,7 @@ func GetTickets(c *gin.Context) {
 	userID := c.GetString("user_id")
 
 	rows, err := config.DB.Query(`
-		SELECT id, ticket_number, user_id, subject, description, status, category, 
-		       dikerjakan_oleh, bukti_masalah, bukti_selesai, created_at, updated_at, resolved_at
+		SELECT `+ticketColumns+`
 		FROM helpdesk_tickets 
 		WHERE user_id = ?
 		ORDER BY created_at DESC
@@ -36,9 +36,7 @@ func GetTickets(c *gin.Context) {
 	tickets := []models.Ticket{}
 	for rows.Next() {
 		var t models.Ticket
-		err := rows.Scan(&t.ID, &t.TicketNumber, &t.UserID, &t.Subject, &t.Description,
-			&t.Status, &t.Category, &t.DikerjakanOleh, &t.BuktiMasalah, &t.BuktiSelesai,
-			&t.CreatedAt, &t.UpdatedAt, &t.ResolvedAt)
+		err := scanTicket(rows, &t)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -46,28 +44,33 @@ func GetTickets(c *gin.Context) {
 		tickets = append(tickets, t)
 	}
 
+	if err := services.LoadTicketFieldValues(tickets); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, tickets)
 }
 
 // GetTicket - Get single ticket by ID
 func GetTicket(c *gin.Context) {
-	userID := c.GetString("user_id")
-	ticketID := c.Param("id")
+	ticket, ok := authorizeTicket(c, c.Param("id"), services.TicketView)
+	if !ok {
+		return
+	}
 
 	var t models.Ticket
-	err := config.DB.QueryRow(`
-		SELECT id, ticket_number, user_id, subject, description, status, category, 
-		       dikerjakan_oleh, bukti_masalah, bukti_selesai, created_at, updated_at, resolved_at
+	err := scanTicket(config.DB.QueryRow(`
+		SELECT `+ticketColumns+`
 		FROM helpdesk_tickets 
-		WHERE id = ? AND user_id = ?
-	`, ticketID, userID).Scan(&t.ID, &t.TicketNumber, &t.UserID, &t.Subject, &t.Description,
-		&t.Status, &t.Category, &t.DikerjakanOleh, &t.BuktiMasalah, &t.BuktiSelesai,
-		&t.CreatedAt, &t.UpdatedAt, &t.ResolvedAt)
-
-	if err == sql.ErrNoRows {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+		WHERE id = ?
+	`, ticket.ID), &t)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+
+	t, err = withCustomFields(t)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -86,14 +89,61 @@ func CreateTicket(c *gin.Context) {
 		return
 	}
 
+	// Category must exist, be active and be a leaf of the category tree
+	category, ok := resolveTicketCategory(req.CategoryID, req.Category)
+	if !ok {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Kategori tidak ditemukan, tidak aktif, atau masih memiliki subkategori"})
+		return
+	}
+	req.Category = category.Name
+
+	// Validate custom fields defined for the category
+	fields, err := services.CategoryFields(category.ID, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	fieldValues, err := services.ValidateCustomFields(fields, req.CustomFields)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Derive priority from urgency and impact
+	if req.Urgency == "" {
+		req.Urgency = services.LevelSedang
+	}
+	if req.Impact == "" {
+		req.Impact = services.LevelSedang
+	}
+	priority, err := services.ComputePriority(req.Urgency, req.Impact)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Generate ticket number
 	ticketNumber := generateTicketNumber()
 
+	teamID, err := services.CategoryTeamID(category.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
 	// Insert ticket
-	result, err := config.DB.Exec(`
-		INSERT INTO helpdesk_tickets (ticket_number, user_id, subject, description, category, status)
-		VALUES (?, ?, ?, ?, ?, 'baru')
-	`, ticketNumber, userID, req.Subject, req.Description, req.Category)
+	result, err := tx.Exec(`
+		INSERT INTO helpdesk_tickets (ticket_number, user_id, subject, description, category, category_id, team_id, urgency, impact, priority, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 'baru')
+	`, ticketNumber, userID, req.Subject, req.Description, category.Name, category.ID, teamID,
+		req.Urgency, req.Impact, priority)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -102,26 +152,53 @@ func CreateTicket(c *gin.Context) {
 
 	id, _ := result.LastInsertId()
 
+	if err := services.SaveTicketFieldValues(tx, int(id), fieldValues); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.RecordTicketEvent(tx, int(id), services.EventCreated, currentActor(c), "", services.StatusBaru); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Notify the ticket's team
+	if err := services.NotifyNewTicket(tx, int(id), ticketNumber, req.Subject, req.Category, priority, c.GetString("user_nama")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.ApplySLAPolicy(tx, int(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Route the ticket to a technician if the category has a routing rule
+	if _, err := services.AutoAssignTicket(int(id)); err != nil {
+		log.Println("Auto assignment failed:", err)
+	}
+
 	// Return created ticket
 	var t models.Ticket
-	config.DB.QueryRow(`
-		SELECT id, ticket_number, user_id, subject, description, status, category, 
-		       dikerjakan_oleh, bukti_masalah, bukti_selesai, created_at, updated_at, resolved_at
+	scanTicket(config.DB.QueryRow(`
+		SELECT `+ticketColumns+`
 		FROM helpdesk_tickets WHERE id = ?
-	`, id).Scan(&t.ID, &t.TicketNumber, &t.UserID, &t.Subject, &t.Description,
-		&t.Status, &t.Category, &t.DikerjakanOleh, &t.BuktiMasalah, &t.BuktiSelesai,
-		&t.CreatedAt, &t.UpdatedAt, &t.ResolvedAt)
-
-	// Get user name for notification
-	userName := c.GetString("user_nama")
+	`, id), &t)
 
-	// Send Telegram notification
-	go services.NotifyNewTicket(ticketNumber, req.Subject, req.Category, userName)
+	// The ticket is created either way; don't report it as a failure
+	if t, err = withCustomFields(t); err != nil {
+		log.Println("Failed to load custom fields of new ticket:", err)
+	}
 
 	c.JSON(http.StatusCreated, t)
 }
 
-// UpdateTicketStatus - Update ticket status
+// UpdateTicketStatus - Update ticket status (by requester)
 func UpdateTicketStatus(c *gin.Context) {
 	ticketID := c.Param("id")
 
@@ -131,28 +208,108 @@ func UpdateTicketStatus(c *gin.Context) {
 		return
 	}
 
-	// Update status
-	query := "UPDATE helpdesk_tickets SET status = ?"
-	args := []interface{}{req.Status}
-
-	// If resolved, set resolved_at
-	if req.Status == "selesai" {
-		query += ", resolved_at = ?"
-		args = append(args, time.Now())
+	if _, ok := authorizeTicket(c, ticketID, services.TicketRequest); !ok {
+		return
 	}
 
-	query += " WHERE id = ?"
-	args = append(args, ticketID)
+	actor := currentActor(c)
+	actor.Role = services.RoleRequester
 
-	_, err := config.DB.Exec(query, args...)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if _, err := services.ChangeTicketStatus(ticketID, req.Status, actor); err != nil {
+		respondWorkflowError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Status updated"})
 }
 
+// currentActor - Build a workflow actor from the JWT claims
+func currentActor(c *gin.Context) services.Actor {
+	return services.Actor{
+		UserID: c.GetString("user_id"),
+		Nama:   c.GetString("user_nama"),
+	}
+}
+
+// authorizeTicket - Load a ticket and check that the caller may perform an action on it.
+// Responds with 404/403 and returns false when not allowed.
+func authorizeTicket(c *gin.Context, ticketID, action string) (services.TicketRef, bool) {
+	ticket, err := services.LoadTicketRef(ticketID)
+	if err == nil {
+		err = services.AuthorizeTicket(ticket, c.GetString("user_id"), c.GetString("user_role"), action)
+	}
+	if err != nil {
+		respondWorkflowError(c, err)
+		return ticket, false
+	}
+	return ticket, true
+}
+
+// respondWorkflowError - Map workflow errors to HTTP responses
+func respondWorkflowError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrTicketNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidStatus), errors.Is(err, services.ErrBuktiSelesaiRequired),
+		errors.Is(err, services.ErrReassignReasonRequired), errors.Is(err, services.ErrStaffCannotWork):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrTransitionNotAllowed), errors.Is(err, services.ErrAlreadyAssigned):
+		status = http.StatusConflict
+	case errors.Is(err, services.ErrTransitionForbidden), errors.Is(err, services.ErrTicketForbidden):
+		status = http.StatusForbidden
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+// ticketColumns - Columns selected for models.Ticket, in the order read by scanTicket
+const ticketColumns = `id, ticket_number, user_id, subject, description, status, category, category_id, team_id, urgency, impact, priority,
+		       assignee_id, dikerjakan_oleh, bukti_masalah, bukti_selesai, created_at, updated_at, resolved_at,
+		       first_response_at, response_due_at, resolution_due_at, bukti_masalah_thumb, bukti_selesai_thumb`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanTicket - Scan a row selected with ticketColumns into a ticket
+func scanTicket(row rowScanner, t *models.Ticket) error {
+	err := row.Scan(&t.ID, &t.TicketNumber, &t.UserID, &t.Subject, &t.Description,
+		&t.Status, &t.Category, &t.CategoryID, &t.TeamID, &t.Urgency, &t.Impact, &t.Priority,
+		&t.AssigneeID, &t.DikerjakanOleh, &t.BuktiMasalah, &t.BuktiSelesai,
+		&t.CreatedAt, &t.UpdatedAt, &t.ResolvedAt,
+		&t.FirstResponseAt, &t.ResponseDueAt, &t.ResolutionDueAt, &t.BuktiMasalahThumb, &t.BuktiSelesaiThumb)
+	if err != nil {
+		return err
+	}
+	services.ComputeSLAFlags(t, time.Now())
+
+	// Uploads are not public; hand out short-lived links for display
+	if t.BuktiMasalah != nil && *t.BuktiMasalah != "" {
+		url := services.SignAttachmentURL(t.ID, "masalah")
+		t.BuktiMasalahURL = &url
+	}
+	if t.BuktiSelesai != nil && *t.BuktiSelesai != "" {
+		url := services.SignAttachmentURL(t.ID, "selesai")
+		t.BuktiSelesaiURL = &url
+	}
+	if t.BuktiMasalahThumb != nil {
+		url := services.SignThumbnailURL(t.ID, "masalah")
+		t.BuktiMasalahThumbURL = &url
+	}
+	if t.BuktiSelesaiThumb != nil {
+		url := services.SignThumbnailURL(t.ID, "selesai")
+		t.BuktiSelesaiThumbURL = &url
+	}
+	return nil
+}
+
+// withCustomFields - Load the custom field values of a single ticket
+func withCustomFields(t models.Ticket) (models.Ticket, error) {
+	tickets := []models.Ticket{t}
+	err := services.LoadTicketFieldValues(tickets)
+	return tickets[0], err
+}
+
 // generateTicketNumber - Generate unique ticket number
 func generateTicketNumber() string {
 	now := time.Now()
@@ -170,8 +327,7 @@ func generateTicketNumber() string {
 // GetAllTickets - Get all tickets (for admin)
 func GetAllTickets(c *gin.Context) {
 	rows, err := config.DB.Query(`
-		SELECT id, ticket_number, user_id, subject, description, status, category, 
-		       dikerjakan_oleh, bukti_masalah, bukti_selesai, created_at, updated_at, resolved_at
+		SELECT ` + ticketColumns + `
 		FROM helpdesk_tickets 
 		ORDER BY created_at DESC
 		LIMIT 100
@@ -186,132 +342,95 @@ func GetAllTickets(c *gin.Context) {
 	tickets := []models.Ticket{}
 	for rows.Next() {
 		var t models.Ticket
-		err := rows.Scan(&t.ID, &t.TicketNumber, &t.UserID, &t.Subject, &t.Description,
-			&t.Status, &t.Category, &t.DikerjakanOleh, &t.BuktiMasalah, &t.BuktiSelesai,
-			&t.CreatedAt, &t.UpdatedAt, &t.ResolvedAt)
+		err := scanTicket(rows, &t)
 		if err != nil {
 			continue
 		}
 		tickets = append(tickets, t)
 	}
 
-	c.JSON(http.StatusOK, tickets)
-}
-
-// AssignTicket - Assign ticket to staff
-func AssignTicket(c *gin.Context) {
-	ticketID := c.Param("id")
-	staffName := c.GetString("user_nama")
-
-	_, err := config.DB.Exec(`
-		UPDATE helpdesk_tickets 
-		SET dikerjakan_oleh = ?, status = 'dikerjakan'
-		WHERE id = ?
-	`, staffName, ticketID)
-
-	if err != nil {
+	if err := services.LoadTicketFieldValues(tickets); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Ticket assigned"})
+	c.JSON(http.StatusOK, tickets)
 }
 
-// UploadBuktiMasalah - Upload proof of problem (by user)
-func UploadBuktiMasalah(c *gin.Context) {
+// AssignTicket - Assign ticket to a staff member (defaults to the caller)
+func AssignTicket(c *gin.Context) {
+	userID := c.GetString("user_id")
 	ticketID := c.Param("id")
 
-	file, err := c.FormFile("bukti")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File required"})
+	var req models.AssignTicketRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Get ticket number
-	var ticketNumber string
-	config.DB.QueryRow(`SELECT ticket_number FROM helpdesk_tickets WHERE id = ?`, ticketID).Scan(&ticketNumber)
-	if ticketNumber == "" {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+	if _, ok := authorizeTicket(c, ticketID, services.TicketAssign); !ok {
 		return
 	}
 
-	// Create folder if not exists
-	os.MkdirAll("./uploads/masalah", os.ModePerm)
-
-	// Generate filename using ticket number
-	filename := fmt.Sprintf("%s%s", ticketNumber, getFileExtension(file.Filename))
-	filepath := "masalah/" + filename
+	actor := currentActor(c)
+	actor.Role = services.RoleAdmin
 
-	// Save file
-	if err := c.SaveUploadedFile(file, "./uploads/"+filepath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// Technicians may only take tickets themselves; dispatching needs ticket:assign
+	if req.AssigneeID == "" {
+		req.AssigneeID = userID
+	}
+	if req.AssigneeID != userID && !hasPermission(c, services.PermTicketAssign) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+	assignee, err := services.FindStaff(req.AssigneeID)
+	if errors.Is(err, services.ErrStaffNotFound) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
 		return
 	}
-
-	// Update database
-	_, err = config.DB.Exec(`
-		UPDATE helpdesk_tickets SET bukti_masalah = ? WHERE id = ?
-	`, filepath, ticketID)
-
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"filename": filepath})
+	if _, err := services.AssignTicket(ticketID, assignee, req.Reason, actor); err != nil {
+		respondWorkflowError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Ticket assigned", "assignee_id": assignee.UserID, "dikerjakan_oleh": assignee.Nama})
+}
+
+// UploadBuktiMasalah - Upload proof of problem (by user)
+func UploadBuktiMasalah(c *gin.Context) {
+	uploadBukti(c, services.AttachmentMasalah, services.TicketRequest)
 }
 
 // UploadBuktiSelesai - Upload proof of completion (by admin)
 func UploadBuktiSelesai(c *gin.Context) {
-	ticketID := c.Param("id")
-
-	file, err := c.FormFile("bukti")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File required"})
-		return
-	}
+	uploadBukti(c, services.AttachmentSelesai, services.TicketWork)
+}
 
-	// Get ticket number
-	var ticketNumber string
-	config.DB.QueryRow(`SELECT ticket_number FROM helpdesk_tickets WHERE id = ?`, ticketID).Scan(&ticketNumber)
-	if ticketNumber == "" {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+// uploadBukti - Store a bukti file as a ticket attachment of the given kind
+func uploadBukti(c *gin.Context, kind, action string) {
+	ticket, ok := authorizeTicket(c, c.Param("id"), action)
+	if !ok {
 		return
 	}
 
-	// Create folder if not exists
-	os.MkdirAll("./uploads/selesai", os.ModePerm)
-
-	// Generate filename using ticket number
-	filename := fmt.Sprintf("%s%s", ticketNumber, getFileExtension(file.Filename))
-	filepath := "selesai/" + filename
-
-	// Save file
-	if err := c.SaveUploadedFile(file, "./uploads/"+filepath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	file, err := c.FormFile("bukti")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File required"})
 		return
 	}
 
-	// Update database
-	_, err = config.DB.Exec(`
-		UPDATE helpdesk_tickets SET bukti_selesai = ? WHERE id = ?
-	`, filepath, ticketID)
-
+	att, err := services.SaveAttachment(ticket.ID, nil, kind, services.FormFile(file), currentActor(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondUploadError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"filename": filepath})
-}
-
-func getFileExtension(filename string) string {
-	for i := len(filename) - 1; i >= 0; i-- {
-		if filename[i] == '.' {
-			return filename[i:]
-		}
-	}
-	return ""
+	c.JSON(http.StatusOK, gin.H{"filename": att.StoredPath, "attachment": att})
 }
 
 // ParseInt helper
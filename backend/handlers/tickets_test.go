@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"helpdesk-backend/config"
+	"helpdesk-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ticketTestRouter serves the ticket routes as the given user, in place of JWTAuth and LoadRole
+func ticketTestRouter(t *testing.T, userID, role string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := sql.Open("helpdesk-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := config.DB
+	config.DB = db
+	t.Cleanup(func() {
+		config.DB = previous
+		db.Close()
+	})
+
+	fakeTickets.tickets["1"] = []driver.Value{int64(1), "HD-20260101-001", "Printer rusak", services.StatusDikerjakan, "owner", "tech1"}
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Set("user_nama", userID)
+		c.Set("user_role", role)
+	})
+	r.GET("/tickets/:id", GetTicket)
+	r.PATCH("/tickets/:id/status", UpdateTicketStatus)
+	r.PUT("/tickets/:id/comments/:commentId", UpdateComment)
+	r.POST("/tickets/:id/assign", AssignTicket)
+	r.PATCH("/admin/tickets/:id", UpdateTicketAdmin)
+	r.POST("/tickets/:id/bukti-masalah", UploadBuktiMasalah)
+	r.POST("/admin/tickets/:id/bukti-selesai", UploadBuktiSelesai)
+	return r
+}
+
+func TestTicketAccess(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID string
+		role   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		// Someone else's ticket does not exist for a normal user
+		{"stranger reads", "stranger", services.RoleRequester, http.MethodGet, "/tickets/1", "", http.StatusNotFound},
+		{"stranger closes", "stranger", services.RoleRequester, http.MethodPatch, "/tickets/1/status", `{"status":"ditutup"}`, http.StatusNotFound},
+		{"stranger edits a comment", "stranger", services.RoleRequester, http.MethodPut, "/tickets/1/comments/1", `{"body":"x"}`, http.StatusNotFound},
+		{"stranger assigns", "stranger", services.RoleRequester, http.MethodPost, "/tickets/1/assign", `{}`, http.StatusNotFound},
+		{"stranger works", "stranger", services.RoleRequester, http.MethodPatch, "/admin/tickets/1", `{"status":"selesai"}`, http.StatusNotFound},
+		{"stranger uploads bukti masalah", "stranger", services.RoleRequester, http.MethodPost, "/tickets/1/bukti-masalah", "", http.StatusNotFound},
+		{"stranger uploads bukti selesai", "stranger", services.RoleRequester, http.MethodPost, "/admin/tickets/1/bukti-selesai", "", http.StatusNotFound},
+		{"missing ticket", "owner", services.RoleRequester, http.MethodGet, "/tickets/2", "", http.StatusNotFound},
+
+		// Visible, but not theirs to work on
+		{"owner works", "owner", services.RoleRequester, http.MethodPatch, "/admin/tickets/1", `{"status":"selesai"}`, http.StatusForbidden},
+		{"owner assigns", "owner", services.RoleRequester, http.MethodPost, "/tickets/1/assign", `{}`, http.StatusForbidden},
+		{"other technician works", "tech2", services.RoleTechnician, http.MethodPatch, "/admin/tickets/1", `{"status":"selesai"}`, http.StatusForbidden},
+		{"other technician takes over", "tech2", services.RoleTechnician, http.MethodPost, "/tickets/1/assign", `{}`, http.StatusForbidden},
+		{"unit manager works", "manager", services.RoleUnitManager, http.MethodPatch, "/admin/tickets/1", `{"status":"selesai"}`, http.StatusForbidden},
+		{"unit manager closes", "manager", services.RoleUnitManager, http.MethodPatch, "/tickets/1/status", `{"status":"ditutup"}`, http.StatusForbidden},
+		{"unit manager uploads bukti masalah", "manager", services.RoleUnitManager, http.MethodPost, "/tickets/1/bukti-masalah", "", http.StatusForbidden},
+		{"owner uploads bukti selesai", "owner", services.RoleRequester, http.MethodPost, "/admin/tickets/1/bukti-selesai", "", http.StatusForbidden},
+		{"other technician uploads bukti selesai", "tech2", services.RoleTechnician, http.MethodPost, "/admin/tickets/1/bukti-selesai", "", http.StatusForbidden},
+		{"unit manager uploads bukti selesai", "manager", services.RoleUnitManager, http.MethodPost, "/admin/tickets/1/bukti-selesai", "", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := ticketTestRouter(t, tt.userID, tt.role)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("got %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
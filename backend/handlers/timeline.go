@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+
+	"helpdesk-backend/config"
+	"helpdesk-backend/models"
+	"helpdesk-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTicketTimeline - Get chronological history of a ticket (events and comments)
+func GetTicketTimeline(c *gin.Context) {
+	ticketID := c.Param("id")
+
+	admin := hasPermission(c, services.PermTicketViewAll)
+	if _, ok := authorizeTicket(c, ticketID, services.TicketView); !ok {
+		return
+	}
+
+	commentFilter := ""
+	if !admin {
+		commentFilter = " AND is_internal = 0"
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT event_type, actor_id, actor_nama, old_value, new_value, note, NULL, 0, created_at, id
+		FROM helpdesk_ticket_events
+		WHERE ticket_id = ?
+		UNION ALL
+		SELECT 'comment', user_id, user_nama, NULL, NULL, NULL, body, is_internal, created_at, id
+		FROM helpdesk_ticket_comments
+		WHERE ticket_id = ?`+commentFilter+`
+		ORDER BY created_at ASC, id ASC
+	`, ticketID, ticketID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	timeline := []models.TimelineEntry{}
+	for rows.Next() {
+		var e models.TimelineEntry
+		var id int
+		if err := rows.Scan(&e.Type, &e.ActorID, &e.ActorNama, &e.OldValue, &e.NewValue, &e.Note,
+			&e.Body, &e.IsInternal, &e.CreatedAt, &id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		timeline = append(timeline, e)
+	}
+
+	c.JSON(http.StatusOK, timeline)
+}
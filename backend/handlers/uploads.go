@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"helpdesk-backend/models"
+	"helpdesk-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateTicketUpload - Start a resumable upload for a large attachment
+func CreateTicketUpload(c *gin.Context) {
+	var req models.UploadSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Kind == "" {
+		req.Kind = services.AttachmentOther
+	}
+	if !services.IsValidAttachmentKind(req.Kind) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrInvalidAttachmentKind.Error()})
+		return
+	}
+
+	ticket, ok := authorizeTicket(c, c.Param("id"), attachmentAction(req.Kind))
+	if !ok {
+		return
+	}
+	if req.CommentID != nil && !commentVisible(c, ticket.ID, *req.CommentID) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Comment not found"})
+		return
+	}
+
+	session, err := services.CreateUploadSession(ticket.ID, req.CommentID, req.Kind, req.Filename, req.Size, req.Checksum, currentActor(c))
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/api/tickets/%d/uploads/%s", ticket.ID, session.ID))
+	setUploadHeaders(c, session)
+	c.JSON(http.StatusCreated, session)
+}
+
+// GetTicketUpload - Show how much of a resumable upload has been received
+func GetTicketUpload(c *gin.Context) {
+	ticket, ok := authorizeTicket(c, c.Param("id"), services.TicketView)
+	if !ok {
+		return
+	}
+	session, ok := loadUploadSession(c, ticket.ID)
+	if !ok {
+		return
+	}
+
+	setUploadHeaders(c, session)
+	c.JSON(http.StatusOK, session)
+}
+
+// PatchTicketUpload - Append a chunk (raw request body) at the Upload-Offset header.
+// The chunk that completes the file creates the attachment.
+func PatchTicketUpload(c *gin.Context) {
+	ticket, ok := authorizeTicket(c, c.Param("id"), services.TicketView)
+	if !ok {
+		return
+	}
+	session, ok := loadUploadSession(c, ticket.ID)
+	if !ok {
+		return
+	}
+	// The ticket may have changed since the upload started
+	if err := services.AuthorizeTicket(ticket, c.GetString("user_id"), c.GetString("user_role"), attachmentAction(session.Kind)); err != nil {
+		respondWorkflowError(c, err)
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Header Upload-Offset wajib diisi"})
+		return
+	}
+
+	// Chunks are stored as they arrive, which needs their size up front
+	if c.Request.ContentLength < 0 {
+		c.JSON(http.StatusLengthRequired, gin.H{"error": "Header Content-Length wajib diisi"})
+		return
+	}
+
+	session, att, err := services.AppendUploadChunk(session, offset, c.Request.Body, c.Request.ContentLength)
+	setUploadHeaders(c, session)
+	if errors.Is(err, services.ErrUploadOffsetMismatch) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "offset": session.Offset})
+		return
+	}
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	if att != nil {
+		c.JSON(http.StatusCreated, gin.H{"upload": session, "attachment": att})
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// DeleteTicketUpload - Cancel a resumable upload
+func DeleteTicketUpload(c *gin.Context) {
+	ticket, ok := authorizeTicket(c, c.Param("id"), services.TicketView)
+	if !ok {
+		return
+	}
+	session, ok := loadUploadSession(c, ticket.ID)
+	if !ok {
+		return
+	}
+
+	if err := services.CancelUploadSession(session); err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Upload cancelled"})
+}
+
+// loadUploadSession - Load the caller's upload session from the URL. Sessions of other users are not found.
+func loadUploadSession(c *gin.Context, ticketID int) (models.UploadSession, bool) {
+	session, err := services.GetUploadSession(ticketID, c.Param("uploadId"))
+	if err == nil && session.UploaderID != c.GetString("user_id") {
+		err = services.ErrUploadNotFound
+	}
+	if err != nil {
+		respondUploadError(c, err)
+		return session, false
+	}
+	return session, true
+}
+
+// setUploadHeaders - Report upload progress the way tus clients expect it
+func setUploadHeaders(c *gin.Context, session models.UploadSession) {
+	if session.ID == "" {
+		return
+	}
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(session.Size, 10))
+	c.Header("Cache-Control", "no-store")
+}
@@ -7,6 +7,7 @@ import (
 	"helpdesk-backend/config"
 	"helpdesk-backend/handlers"
 	"helpdesk-backend/middleware"
+	"helpdesk-backend/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,9 +15,20 @@ import (
 func main() {
 	// Connect to database
 	config.ConnectDatabase()
+	config.MigrateDatabase()
 
-	// Create uploads directory
-	os.MkdirAll("./uploads", os.ModePerm)
+	// Notification channels (Telegram, email, webhooks), the outbox worker and the Telegram bot
+	services.InitNotifiers()
+	services.StartNotificationWorker()
+	services.StartTelegramBot()
+
+	// Start background SLA checker
+	services.StartSLAChecker()
+
+	// Attachment storage (local disk or S3-compatible)
+	services.InitStorage()
+	services.InitScanner()
+	services.StartUploadCleanup()
 
 	// Setup Gin router
 	r := gin.Default()
@@ -24,8 +36,9 @@ func main() {
 	// CORS middleware
 	r.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Methods", "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, Upload-Offset")
+		c.Header("Access-Control-Expose-Headers", "Location, Upload-Offset, Upload-Length")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -34,37 +47,120 @@ func main() {
 		c.Next()
 	})
 
-	// Serve uploaded files
-	r.Static("/uploads", "./uploads")
-
 	// API routes
 	api := r.Group("/api")
 	{
+		// Signed attachment links (no JWT; the signature grants access)
+		api.GET("/files/tickets/:id/attachments/:attachmentId", handlers.GetSignedAttachment)
+		api.GET("/files/tickets/:id/attachments/:attachmentId/thumbnail", handlers.GetSignedAttachmentThumbnail)
+
+		// Telegram bot updates (authenticated by TELEGRAM_WEBHOOK_SECRET)
+		api.POST("/telegram/webhook", handlers.TelegramWebhook)
+
 		// Protected routes (require JWT)
 		protected := api.Group("")
-		protected.Use(middleware.JWTAuth())
+		protected.Use(middleware.JWTAuth(), middleware.LoadRole())
+
+		// Permission shorthands for route declarations below
+		viewAll := middleware.RequirePermission(services.PermTicketViewAll)
+		work := middleware.RequirePermission(services.PermTicketWork)
+		dispatch := middleware.RequirePermission(services.PermTicketAssign)
+		reports := middleware.RequirePermission(services.PermReportView)
+		manage := middleware.RequirePermission(services.PermConfigManage)
+		staffAdmin := middleware.RequirePermission(services.PermStaffManage)
 		{
 			// Tickets
 			protected.GET("/tickets", handlers.GetTickets)
 			protected.GET("/tickets/:id", handlers.GetTicket)
 			protected.POST("/tickets", handlers.CreateTicket)
 			protected.PATCH("/tickets/:id/status", handlers.UpdateTicketStatus)
-			protected.POST("/tickets/:id/assign", handlers.AssignTicket)
+			protected.POST("/tickets/:id/assign", work, handlers.AssignTicket)
 			protected.POST("/tickets/:id/bukti-masalah", handlers.UploadBuktiMasalah)
-			protected.POST("/tickets/:id/bukti-selesai", handlers.UploadBuktiSelesai)
+			protected.POST("/tickets/:id/bukti-selesai", work, handlers.UploadBuktiSelesai)
+
+			// Attachments
+			protected.GET("/tickets/:id/attachments", handlers.GetTicketAttachments)
+			protected.POST("/tickets/:id/attachments", handlers.UploadTicketAttachment)
+			protected.GET("/tickets/:id/attachments/:attachmentId", handlers.GetTicketAttachment)
+			protected.GET("/tickets/:id/attachments/:attachmentId/thumbnail", handlers.GetTicketAttachmentThumbnail)
+			protected.DELETE("/tickets/:id/attachments/:attachmentId", handlers.DeleteTicketAttachment)
+			protected.POST("/tickets/:id/uploads", handlers.CreateTicketUpload)
+			protected.GET("/tickets/:id/uploads/:uploadId", handlers.GetTicketUpload)
+			protected.HEAD("/tickets/:id/uploads/:uploadId", handlers.GetTicketUpload)
+			protected.PATCH("/tickets/:id/uploads/:uploadId", handlers.PatchTicketUpload)
+			protected.DELETE("/tickets/:id/uploads/:uploadId", handlers.DeleteTicketUpload)
+
+			// Comments
+			protected.GET("/tickets/:id/comments", handlers.GetComments)
+			protected.POST("/tickets/:id/comments", handlers.CreateComment)
+			protected.PUT("/tickets/:id/comments/:commentId", handlers.UpdateComment)
+
+			// History
+			protected.GET("/tickets/:id/timeline", handlers.GetTicketTimeline)
 
 			// Categories
 			protected.GET("/categories", handlers.GetCategories)
+			protected.GET("/categories/:id/fields", handlers.GetCategoryFields)
 
 			// Dashboard
 			protected.GET("/dashboard/stats", handlers.GetDashboardStats)
 			protected.GET("/dashboard/recent", handlers.GetRecentTickets)
 
+			// Telegram account link
+			protected.GET("/telegram/link", handlers.GetTelegramLink)
+			protected.POST("/telegram/link", handlers.CreateTelegramLink)
+			protected.DELETE("/telegram/link", handlers.DeleteTelegramLink)
+
 			// Auth & Admin
 			protected.GET("/auth/info", handlers.GetAuthInfo)
-			protected.GET("/admin/tickets", handlers.GetAllTicketsAdmin)
-			protected.GET("/admin/dashboard/stats", handlers.GetAdminDashboardStats)
-			protected.PATCH("/admin/tickets/:id", handlers.UpdateTicketAdmin)
+			protected.GET("/admin/tickets", viewAll, handlers.GetAllTicketsAdmin)
+			protected.GET("/admin/dashboard/stats", viewAll, handlers.GetAdminDashboardStats)
+			protected.PATCH("/admin/tickets/:id", work, handlers.UpdateTicketAdmin)
+			protected.PATCH("/admin/tickets/:id/priority", dispatch, handlers.UpdateTicketPriority)
+
+			// SLA policies (admin)
+			protected.GET("/admin/sla-policies", manage, handlers.GetSLAPolicies)
+			protected.POST("/admin/sla-policies", manage, handlers.CreateSLAPolicy)
+			protected.PUT("/admin/sla-policies/:id", manage, handlers.UpdateSLAPolicy)
+			protected.DELETE("/admin/sla-policies/:id", manage, handlers.DeleteSLAPolicy)
+
+			// Routing & staff (admin)
+			protected.GET("/admin/routing-rules", manage, handlers.GetRoutingRules)
+			protected.PUT("/admin/routing-rules", manage, handlers.SaveRoutingRule)
+			protected.DELETE("/admin/routing-rules/:categoryId", manage, handlers.DeleteRoutingRule)
+			protected.GET("/admin/staff", viewAll, handlers.GetStaff)
+			protected.POST("/admin/staff", staffAdmin, handlers.GrantStaff)
+			protected.DELETE("/admin/staff/:userId", staffAdmin, handlers.RevokeStaff)
+			protected.GET("/admin/staff/audit", staffAdmin, handlers.GetStaffAudit)
+			protected.PUT("/admin/staff/:userId/role", staffAdmin, handlers.UpdateStaffRole)
+			protected.PUT("/admin/staff/:userId/availability", manage, handlers.UpdateStaffAvailability)
+			protected.PUT("/admin/staff/:userId/skills", manage, handlers.UpdateStaffSkills)
+
+			// Notification outbox (admin)
+			protected.GET("/admin/notifications/outbox", manage, handlers.GetNotificationOutbox)
+			protected.POST("/admin/notifications/outbox/:id/retry", manage, handlers.RetryNotificationDelivery)
+
+			// Teams (admin)
+			protected.GET("/admin/teams", manage, handlers.GetTeams)
+			protected.POST("/admin/teams", manage, handlers.CreateTeam)
+			protected.PUT("/admin/teams/:id", manage, handlers.UpdateTeam)
+			protected.DELETE("/admin/teams/:id", manage, handlers.DeleteTeam)
+			protected.PUT("/admin/teams/:id/members", manage, handlers.UpdateTeamMembers)
+			protected.PUT("/admin/categories/:id/team", manage, handlers.UpdateCategoryTeam)
+
+			// Categories (admin)
+			protected.GET("/admin/categories", manage, handlers.GetAllCategoriesAdmin)
+			protected.POST("/admin/categories", manage, handlers.CreateCategory)
+			protected.PUT("/admin/categories/order", manage, handlers.ReorderCategories)
+			protected.PUT("/admin/categories/:id", manage, handlers.UpdateCategory)
+			protected.DELETE("/admin/categories/:id", manage, handlers.DeleteCategory)
+			protected.GET("/admin/categories/:id/fields", manage, handlers.GetCategoryFieldsAdmin)
+			protected.POST("/admin/categories/:id/fields", manage, handlers.CreateCategoryField)
+			protected.PUT("/admin/category-fields/:fieldId", manage, handlers.UpdateCategoryField)
+			protected.DELETE("/admin/category-fields/:fieldId", manage, handlers.DeleteCategoryField)
+
+			// Reports (admin)
+			protected.GET("/admin/reports/categories", reports, handlers.GetCategoryReport)
 		}
 	}
 
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+
+	"helpdesk-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoadRole looks up the helpdesk role of the authenticated user.
+// Must run after JWTAuth.
+func LoadRole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := services.UserRole(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		c.Set("user_role", role)
+		c.Next()
+	}
+}
+
+// RequirePermission allows the request only if the user's role grants all given permissions.
+// Must run after LoadRole.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("user_role")
+		for _, p := range permissions {
+			if !services.RoleHasPermission(role, p) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
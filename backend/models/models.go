@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Ticket struct {
 	ID             int        `json:"id"`
@@ -10,18 +13,76 @@ type Ticket struct {
 	Description    string     `json:"description"`
 	Status         string     `json:"status"`
 	Category       string     `json:"category"`
+	CategoryID     *int       `json:"category_id"`
+	TeamID         *int       `json:"team_id"`
+	Urgency        string     `json:"urgency"`
+	Impact         string     `json:"impact"`
+	Priority       string     `json:"priority"`
+	AssigneeID     *string    `json:"assignee_id"`
 	DikerjakanOleh *string    `json:"dikerjakan_oleh"`
 	BuktiMasalah   *string    `json:"bukti_masalah"`
 	BuktiSelesai   *string    `json:"bukti_selesai"`
 	CreatedAt      time.Time  `json:"created_at"`
 	UpdatedAt      time.Time  `json:"updated_at"`
 	ResolvedAt     *time.Time `json:"resolved_at"`
+
+	FirstResponseAt       *time.Time `json:"first_response_at"`
+	ResponseDueAt         *time.Time `json:"response_due_at"`
+	ResolutionDueAt       *time.Time `json:"resolution_due_at"`
+	SLAResponseBreached   bool       `json:"sla_response_breached"`
+	SLAResolutionBreached bool       `json:"sla_resolution_breached"`
+	SLAAtRisk             bool       `json:"sla_at_risk"`
+
+	// Short-lived signed links to the bukti files
+	BuktiMasalahURL *string `json:"bukti_masalah_url"`
+	BuktiSelesaiURL *string `json:"bukti_selesai_url"`
+
+	// Thumbnails of image bukti (nil for other files and older uploads)
+	BuktiMasalahThumb    *string `json:"-"`
+	BuktiSelesaiThumb    *string `json:"-"`
+	BuktiMasalahThumbURL *string `json:"bukti_masalah_thumb_url"`
+	BuktiSelesaiThumbURL *string `json:"bukti_selesai_thumb_url"`
+
+	CustomFields []TicketFieldValue `json:"custom_fields"`
+}
+
+type TicketFieldValue struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
 }
 
 type Category struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"`
+	ID          int        `json:"id"`
+	ParentID    *int       `json:"parent_id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	TeamID      *int       `json:"team_id"`
+	SortOrder   int        `json:"sort_order"`
+	IsActive    bool       `json:"is_active"`
+	Children    []Category `json:"children,omitempty"`
+}
+
+type CategoryRequest struct {
+	ParentID    *int   `json:"parent_id"`
+	Name        string `json:"name" binding:"required"`
 	Description string `json:"description"`
+	TeamID      *int   `json:"team_id"`
+	SortOrder   *int   `json:"sort_order"`
+	IsActive    *bool  `json:"is_active"`
+}
+
+type CategoryReport struct {
+	ID          int              `json:"id"`
+	Name        string           `json:"name"`
+	TicketCount int              `json:"ticket_count"`
+	TotalCount  int              `json:"total_count"`
+	Children    []CategoryReport `json:"children,omitempty"`
+}
+
+type CategoryOrderRequest struct {
+	IDs []int `json:"ids" binding:"required"`
 }
 
 type DashboardStats struct {
@@ -36,8 +97,236 @@ type CreateTicketRequest struct {
 	Subject     string `json:"subject" binding:"required"`
 	Description string `json:"description" binding:"required"`
 	Category    string `json:"category"`
+	CategoryID  *int   `json:"category_id"`
+	Urgency     string `json:"urgency"`
+	Impact      string `json:"impact"`
+
+	CustomFields map[string]interface{} `json:"custom_fields"`
 }
 
 type UpdateStatusRequest struct {
 	Status string `json:"status" binding:"required"`
 }
+
+type AssignTicketRequest struct {
+	AssigneeID string `json:"assignee_id"`
+	Reason     string `json:"reason"`
+}
+
+type UpdatePriorityRequest struct {
+	Urgency string `json:"urgency" binding:"required"`
+	Impact  string `json:"impact" binding:"required"`
+}
+
+type Comment struct {
+	ID         int       `json:"id"`
+	TicketID   int       `json:"ticket_id"`
+	UserID     string    `json:"user_id"`
+	UserNama   string    `json:"user_nama"`
+	Body       string    `json:"body"`
+	IsInternal bool      `json:"is_internal"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type CreateCommentRequest struct {
+	Body       string `json:"body" binding:"required"`
+	IsInternal bool   `json:"is_internal"`
+}
+
+type UpdateCommentRequest struct {
+	Body       string `json:"body" binding:"required"`
+	IsInternal *bool  `json:"is_internal"`
+}
+
+type TimelineEntry struct {
+	Type       string    `json:"type"`
+	ActorID    string    `json:"actor_id"`
+	ActorNama  string    `json:"actor_nama"`
+	OldValue   *string   `json:"old_value"`
+	NewValue   *string   `json:"new_value"`
+	Note       *string   `json:"note"`
+	Body       *string   `json:"body"`
+	IsInternal bool      `json:"is_internal"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type SLAPolicy struct {
+	ID                int       `json:"id"`
+	CategoryID        *int      `json:"category_id"`
+	Category          *string   `json:"category"`
+	Priority          *string   `json:"priority"`
+	ResponseMinutes   int       `json:"response_minutes"`
+	ResolutionMinutes int       `json:"resolution_minutes"`
+	IsActive          bool      `json:"is_active"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type SLAPolicyRequest struct {
+	CategoryID        *int    `json:"category_id"`
+	Priority          *string `json:"priority"`
+	ResponseMinutes   int     `json:"response_minutes" binding:"required,min=1"`
+	ResolutionMinutes int     `json:"resolution_minutes" binding:"required,min=1"`
+	IsActive          *bool   `json:"is_active"`
+}
+
+type RoutingRule struct {
+	CategoryID     int       `json:"category_id"`
+	Category       string    `json:"category"`
+	Strategy       string    `json:"strategy"`
+	LastAssigneeID *string   `json:"last_assignee_id"`
+	IsActive       bool      `json:"is_active"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type RoutingRuleRequest struct {
+	CategoryID int    `json:"category_id" binding:"required"`
+	Strategy   string `json:"strategy" binding:"required"`
+	IsActive   *bool  `json:"is_active"`
+}
+
+type StaffAvailabilityRequest struct {
+	IsAvailable bool `json:"is_available"`
+}
+
+type StaffSkillsRequest struct {
+	CategoryIDs []int `json:"category_ids"`
+}
+
+type Team struct {
+	ID             int       `json:"id"`
+	Name           string    `json:"name"`
+	Description    string    `json:"description"`
+	TelegramChatID *string   `json:"telegram_chat_id"`
+	Members        []string  `json:"members"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type TeamRequest struct {
+	Name           string  `json:"name" binding:"required"`
+	Description    string  `json:"description"`
+	TelegramChatID *string `json:"telegram_chat_id"`
+}
+
+type TeamMembersRequest struct {
+	UserIDs []string `json:"user_ids"`
+}
+
+type CategoryTeamRequest struct {
+	TeamID *int `json:"team_id"`
+}
+
+type CategoryField struct {
+	ID         int      `json:"id"`
+	CategoryID int      `json:"category_id"`
+	Key        string   `json:"key"`
+	Label      string   `json:"label"`
+	Type       string   `json:"type"`
+	Options    []string `json:"options"`
+	IsRequired bool     `json:"is_required"`
+	SortOrder  int      `json:"sort_order"`
+	IsActive   bool     `json:"is_active"`
+}
+
+type CategoryFieldRequest struct {
+	Key        string   `json:"key" binding:"required"`
+	Label      string   `json:"label" binding:"required"`
+	Type       string   `json:"type" binding:"required"`
+	Options    []string `json:"options"`
+	IsRequired bool     `json:"is_required"`
+	SortOrder  int      `json:"sort_order"`
+	IsActive   *bool    `json:"is_active"`
+}
+
+type StaffGrantRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	Role   string `json:"role"`
+}
+
+type StaffRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type AdminAuditEntry struct {
+	ID         int       `json:"id"`
+	Action     string    `json:"action"`
+	TargetID   string    `json:"target_id"`
+	TargetNama string    `json:"target_nama"`
+	Role       *string   `json:"role"`
+	ActorID    string    `json:"actor_id"`
+	ActorNama  string    `json:"actor_nama"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type Attachment struct {
+	ID           int       `json:"id"`
+	TicketID     int       `json:"ticket_id"`
+	CommentID    *int      `json:"comment_id"`
+	Kind         string    `json:"kind"`
+	OriginalName string    `json:"original_name"`
+	MimeType     string    `json:"mime_type"`
+	Size         int64     `json:"size"`
+	UploaderID   string    `json:"uploader_id"`
+	UploaderNama string    `json:"uploader_nama"`
+	CreatedAt    time.Time `json:"created_at"`
+	ScanStatus   string    `json:"scan_status"`
+	URL          string    `json:"url"`
+	ThumbnailURL *string   `json:"thumbnail_url"`
+
+	StoredPath    string  `json:"-"`
+	ThumbnailPath *string `json:"-"`
+}
+
+// UploadSession is a resumable upload in progress
+type UploadSession struct {
+	ID           string    `json:"id"`
+	TicketID     int       `json:"ticket_id"`
+	CommentID    *int      `json:"comment_id"`
+	Kind         string    `json:"kind"`
+	Filename     string    `json:"filename"`
+	Size         int64     `json:"size"`
+	Offset       int64     `json:"offset"`
+	Checksum     string    `json:"checksum"`
+	UploaderID   string    `json:"uploader_id"`
+	UploaderNama string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type UploadSessionRequest struct {
+	Filename  string `json:"filename" binding:"required"`
+	Size      int64  `json:"size" binding:"required"`
+	Checksum  string `json:"checksum" binding:"required"` // SHA-256 of the whole file, hex
+	Kind      string `json:"kind"`
+	CommentID *int   `json:"comment_id"`
+}
+
+// NotificationDelivery is a notification queued for one channel in the outbox
+type NotificationDelivery struct {
+	ID            int64           `json:"id"`
+	Channel       string          `json:"channel"`
+	Event         string          `json:"event"`
+	TicketID      *int            `json:"ticket_id"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	LastError     *string         `json:"last_error"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+	SentAt        *time.Time      `json:"sent_at"`
+	Notification  json.RawMessage `json:"notification"`
+}
+
+// TelegramLink is a helpdesk user's linked Telegram account
+type TelegramLink struct {
+	Linked           bool       `json:"linked"`
+	TelegramUsername string     `json:"telegram_username,omitempty"`
+	LinkedAt         *time.Time `json:"linked_at,omitempty"`
+}
+
+// TelegramLinkCode is a one-time code that links a Telegram chat through the bot
+type TelegramLinkCode struct {
+	Code      string    `json:"code"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
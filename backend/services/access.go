@@ -0,0 +1,72 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+
+	"helpdesk-backend/config"
+)
+
+// Ticket actions checked by AuthorizeTicket
+const (
+	TicketView    = "view"    // read the ticket, its comments and timeline; comment on it
+	TicketRequest = "request" // requester actions: close/reopen, upload bukti masalah
+	TicketWork    = "work"    // staff actions: status changes, upload bukti selesai
+	TicketAssign  = "assign"  // assign or reassign, change priority
+)
+
+var ErrTicketForbidden = errors.New("Anda tidak berhak melakukan aksi ini pada tiket")
+
+// TicketRef is the ownership data of a ticket used for authorization
+type TicketRef struct {
+	ID           int
+	TicketNumber string
+	Subject      string
+	Status       string
+	OwnerID      string
+	AssigneeID   *string
+}
+
+// LoadTicketRef reads the ownership data of a ticket
+func LoadTicketRef(ticketID string) (TicketRef, error) {
+	var t TicketRef
+	err := config.DB.QueryRow(`
+		SELECT id, ticket_number, subject, status, user_id, assignee_id
+		FROM helpdesk_tickets WHERE id = ?
+	`, ticketID).Scan(&t.ID, &t.TicketNumber, &t.Subject, &t.Status, &t.OwnerID, &t.AssigneeID)
+	if err == sql.ErrNoRows {
+		return t, ErrTicketNotFound
+	}
+	return t, err
+}
+
+// AuthorizeTicket decides whether a user with the given role may perform an action on a ticket.
+// Callers that cannot even see the ticket get ErrTicketNotFound so its existence is not revealed;
+// callers that can see it but not perform the action get ErrTicketForbidden.
+func AuthorizeTicket(t TicketRef, userID, role, action string) error {
+	owner := t.OwnerID == userID
+	assignee := t.AssigneeID != nil && *t.AssigneeID == userID
+
+	if !owner && !assignee && !RoleHasPermission(role, PermTicketViewAll) {
+		return ErrTicketNotFound
+	}
+
+	allowed := false
+	switch action {
+	case TicketView:
+		allowed = true
+	case TicketRequest:
+		allowed = owner || RoleHasPermission(role, PermTicketWork)
+	case TicketWork:
+		// Technicians work on their own and unassigned tickets; dispatchers on any
+		allowed = RoleHasPermission(role, PermTicketWork) &&
+			(assignee || t.AssigneeID == nil || RoleHasPermission(role, PermTicketAssign))
+	case TicketAssign:
+		allowed = RoleHasPermission(role, PermTicketAssign) ||
+			(RoleHasPermission(role, PermTicketWork) && (assignee || t.AssigneeID == nil))
+	}
+	if !allowed {
+		return ErrTicketForbidden
+	}
+	return nil
+}
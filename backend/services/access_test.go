@@ -0,0 +1,62 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAuthorizeTicket(t *testing.T) {
+	tech1 := "tech1"
+	assigned := TicketRef{ID: 1, OwnerID: "owner", AssigneeID: &tech1}
+	unassigned := TicketRef{ID: 2, OwnerID: "owner"}
+
+	type user struct {
+		id   string
+		role string
+	}
+	var (
+		owner       = user{"owner", RoleRequester}
+		assignee    = user{"tech1", RoleTechnician}
+		technician  = user{"tech2", RoleTechnician}
+		unitManager = user{"manager", RoleUnitManager}
+		stranger    = user{"stranger", RoleRequester}
+	)
+
+	tests := []struct {
+		name   string
+		ticket TicketRef
+		user   user
+		want   map[string]error // by action
+	}{
+		{"owner", assigned, owner, map[string]error{
+			TicketView: nil, TicketRequest: nil, TicketWork: ErrTicketForbidden, TicketAssign: ErrTicketForbidden,
+		}},
+		{"assignee", assigned, assignee, map[string]error{
+			TicketView: nil, TicketRequest: nil, TicketWork: nil, TicketAssign: nil,
+		}},
+		{"other technician", assigned, technician, map[string]error{
+			TicketView: nil, TicketRequest: nil, TicketWork: ErrTicketForbidden, TicketAssign: ErrTicketForbidden,
+		}},
+		{"other technician, unassigned ticket", unassigned, technician, map[string]error{
+			TicketView: nil, TicketRequest: nil, TicketWork: nil, TicketAssign: nil,
+		}},
+		{"unit manager", assigned, unitManager, map[string]error{
+			TicketView: nil, TicketRequest: ErrTicketForbidden, TicketWork: ErrTicketForbidden, TicketAssign: ErrTicketForbidden,
+		}},
+		{"stranger", assigned, stranger, map[string]error{
+			TicketView: ErrTicketNotFound, TicketRequest: ErrTicketNotFound, TicketWork: ErrTicketNotFound, TicketAssign: ErrTicketNotFound,
+		}},
+		{"stranger, unassigned ticket", unassigned, stranger, map[string]error{
+			TicketView: ErrTicketNotFound, TicketRequest: ErrTicketNotFound, TicketWork: ErrTicketNotFound, TicketAssign: ErrTicketNotFound,
+		}},
+	}
+
+	for _, tt := range tests {
+		for _, action := range []string{TicketView, TicketRequest, TicketWork, TicketAssign} {
+			err := AuthorizeTicket(tt.ticket, tt.user.id, tt.user.role, action)
+			if want := tt.want[action]; !errors.Is(err, want) {
+				t.Errorf("%s, %s: got %v, want %v", tt.name, action, err, want)
+			}
+		}
+	}
+}
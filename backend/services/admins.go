@@ -0,0 +1,181 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+
+	"helpdesk-backend/config"
+	"helpdesk-backend/models"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Admin audit actions
+const (
+	AuditGrant  = "grant"
+	AuditRevoke = "revoke"
+	AuditRole   = "role"
+)
+
+var (
+	ErrPegawaiNotFound = errors.New("Pegawai tidak ditemukan di SIK")
+	ErrAlreadyStaff    = errors.New("Pegawai sudah menjadi staf helpdesk")
+	ErrRevokeSelf      = errors.New("Tidak dapat mencabut akses diri sendiri")
+	ErrChangeOwnRole   = errors.New("Tidak dapat mengubah role diri sendiri")
+)
+
+// FindPegawai returns the name of an employee in the SIK pegawai table
+func FindPegawai(nik string) (string, error) {
+	var nama string
+	err := config.DB.QueryRow(`SELECT nama FROM pegawai WHERE nik = ?`, nik).Scan(&nama)
+	if err == sql.ErrNoRows {
+		return "", ErrPegawaiNotFound
+	}
+	return nama, err
+}
+
+// GrantStaff gives an employee helpdesk staff access with the given role
+// (technician by default) and records it in the audit log
+func GrantStaff(userID, role string, actor Actor) (Staff, error) {
+	if role == "" {
+		role = RoleTechnician
+	}
+	if !IsValidStaffRole(role) {
+		return Staff{}, ErrInvalidRole
+	}
+	nama, err := FindPegawai(userID)
+	if err != nil {
+		return Staff{}, err
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return Staff{}, err
+	}
+	defer tx.Rollback()
+
+	// Lock the user's row (or its gap) so concurrent grants are serialized
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM helpdesk_admins WHERE user_id = ? FOR UPDATE`, userID).Scan(&count); err != nil {
+		return Staff{}, err
+	}
+	if count > 0 {
+		return Staff{}, ErrAlreadyStaff
+	}
+
+	if _, err := tx.Exec(`INSERT INTO helpdesk_admins (user_id, role) VALUES (?, ?)`, userID, role); err != nil {
+		// A concurrent grant of the same user either hit the primary key (1062) or
+		// deadlocked on the shared gap lock (1213) and lost to the other one
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && (mysqlErr.Number == 1062 || mysqlErr.Number == 1213) {
+			return Staff{}, ErrAlreadyStaff
+		}
+		return Staff{}, err
+	}
+	if err := recordAdminAudit(tx, AuditGrant, userID, nama, role, actor); err != nil {
+		return Staff{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Staff{}, err
+	}
+
+	return FindStaff(userID)
+}
+
+// RevokeStaff removes helpdesk staff access together with team memberships and skills.
+// Tickets already assigned to the user keep their assignee.
+func RevokeStaff(userID string, actor Actor) error {
+	if userID == actor.UserID {
+		return ErrRevokeSelf
+	}
+	staff, err := FindStaff(userID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		`DELETE FROM helpdesk_team_members WHERE user_id = ?`,
+		`DELETE FROM helpdesk_staff_skills WHERE user_id = ?`,
+		`DELETE FROM helpdesk_admins WHERE user_id = ?`,
+	} {
+		if _, err := tx.Exec(query, userID); err != nil {
+			return err
+		}
+	}
+	if err := recordAdminAudit(tx, AuditRevoke, userID, staff.Nama, staff.Role, actor); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetStaffRole changes the role of a staff member and records it in the audit log
+func SetStaffRole(userID, role string, actor Actor) error {
+	if !IsValidStaffRole(role) {
+		return ErrInvalidRole
+	}
+	if userID == actor.UserID {
+		return ErrChangeOwnRole
+	}
+	staff, err := FindStaff(userID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE helpdesk_admins SET role = ? WHERE user_id = ?`, role, userID); err != nil {
+		return err
+	}
+	if err := recordAdminAudit(tx, AuditRole, userID, staff.Nama, role, actor); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListAdminAudit returns the most recent audit entries, optionally for one user
+func ListAdminAudit(targetID string, limit int) ([]models.AdminAuditEntry, error) {
+	query := `
+		SELECT id, action, target_id, target_nama, role, actor_id, actor_nama, created_at
+		FROM helpdesk_admin_audit`
+	args := []interface{}{}
+	if targetID != "" {
+		query += " WHERE target_id = ?"
+		args = append(args, targetID)
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := config.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AdminAuditEntry{}
+	for rows.Next() {
+		var e models.AdminAuditEntry
+		if err := rows.Scan(&e.ID, &e.Action, &e.TargetID, &e.TargetNama, &e.Role, &e.ActorID, &e.ActorNama, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func recordAdminAudit(db Execer, action, targetID, targetNama, role string, actor Actor) error {
+	_, err := db.Exec(`
+		INSERT INTO helpdesk_admin_audit (action, target_id, target_nama, role, actor_id, actor_nama)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, action, targetID, targetNama, nullIfEmpty(role), actor.UserID, actor.Nama)
+	return err
+}
@@ -0,0 +1,399 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"helpdesk-backend/config"
+	"helpdesk-backend/models"
+)
+
+// UploadDir is the default directory of the local attachment storage. It is not
+// served publicly; files are only reachable through the attachment endpoints.
+const UploadDir = "./uploads"
+
+// Attachment kinds
+const (
+	AttachmentMasalah = "masalah"
+	AttachmentSelesai = "selesai"
+	AttachmentOther   = "other"
+)
+
+var (
+	ErrAttachmentNotFound    = errors.New("Attachment not found")
+	ErrInvalidAttachmentKind = errors.New("Jenis lampiran tidak valid")
+)
+
+// BuktiColumns maps the bukti kinds to the ticket columns that point at their latest file
+var BuktiColumns = map[string]string{
+	AttachmentMasalah: "bukti_masalah",
+	AttachmentSelesai: "bukti_selesai",
+}
+
+// BuktiThumbColumns maps the bukti kinds to the ticket columns holding their thumbnails
+var BuktiThumbColumns = map[string]string{
+	AttachmentMasalah: "bukti_masalah_thumb",
+	AttachmentSelesai: "bukti_selesai_thumb",
+}
+
+// IsValidAttachmentKind reports whether kind is one of the attachment kinds
+func IsValidAttachmentKind(kind string) bool {
+	return kind == AttachmentMasalah || kind == AttachmentSelesai || kind == AttachmentOther
+}
+
+// SaveAttachment validates an uploaded file, stores it under a random name and records it on the ticket.
+// Images are re-encoded without metadata and get a thumbnail (see ProcessImage).
+// Bukti kinds also become the ticket's current bukti_masalah/bukti_selesai.
+// Files flagged by the UploadScanner are quarantined and recorded as rejected (ErrFileInfected).
+func SaveAttachment(ticketID int, commentID *int, kind string, file UploadedFile, uploader Actor) (models.Attachment, error) {
+	return saveAttachment(ticketID, commentID, kind, file, uploader, nil)
+}
+
+// saveAttachment is SaveAttachment with onRecord, if set, run in the transaction that
+// records the attachment. An error from onRecord discards the attachment.
+func saveAttachment(ticketID int, commentID *int, kind string, file UploadedFile, uploader Actor, onRecord func(tx *sql.Tx, attachmentID int) error) (models.Attachment, error) {
+	if !IsValidAttachmentKind(kind) {
+		return models.Attachment{}, ErrInvalidAttachmentKind
+	}
+
+	checked, err := ValidateUpload(file)
+	if err != nil {
+		return models.Attachment{}, err
+	}
+
+	scanStatus, scan, err := scanUpload(file)
+	if err != nil {
+		return models.Attachment{}, err
+	}
+
+	storedPath, err := randomUploadPath(ticketID, checked.Extension)
+	if err != nil {
+		return models.Attachment{}, err
+	}
+	stored := storedUpload{Path: storedPath, MimeType: checked.MimeType, Size: file.Size, ScanStatus: scanStatus, Signature: scan.Signature}
+
+	switch {
+	case scanStatus == ScanRejected:
+		// Kept as uploaded for inspection, outside the ticket files
+		stored.Path = quarantinePrefix + storedPath
+		err = writeUpload(file, stored.Path, "application/octet-stream")
+	case IsProcessableImage(checked.MimeType):
+		stored.Size, stored.ThumbPath, err = writeImageUpload(file, storedPath, checked.MimeType)
+	default:
+		err = writeUpload(file, storedPath, checked.MimeType)
+	}
+	if err != nil {
+		return models.Attachment{}, err
+	}
+
+	att, err := recordAttachment(ticketID, commentID, kind, checked.SafeName, stored, uploader, onRecord)
+	if err != nil {
+		FileStorage.Delete(stored.Path)
+		if stored.ThumbPath != nil {
+			FileStorage.Delete(*stored.ThumbPath)
+		}
+		return models.Attachment{}, err
+	}
+
+	if scanStatus == ScanRejected {
+		log.Printf("Quarantined upload %q on ticket %d: %s", checked.SafeName, ticketID, scan.Signature)
+		return att, fmt.Errorf("%w (%s)", ErrFileInfected, scan.Signature)
+	}
+	return att, nil
+}
+
+// Storage key prefix of quarantined files
+const quarantinePrefix = "quarantine/"
+
+// storedUpload describes a file written to storage by SaveAttachment
+type storedUpload struct {
+	Path       string
+	ThumbPath  *string
+	MimeType   string
+	Size       int64
+	ScanStatus string
+	Signature  string // malware found by the scanner
+}
+
+func recordAttachment(ticketID int, commentID *int, kind, originalName string, stored storedUpload, uploader Actor, onRecord func(tx *sql.Tx, attachmentID int) error) (models.Attachment, error) {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return models.Attachment{}, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO helpdesk_attachments (ticket_id, comment_id, kind, original_name, stored_path, thumbnail_path, mime_type, size, scan_status, uploader_id, uploader_nama)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, ticketID, commentID, kind, originalName, stored.Path, stored.ThumbPath, stored.MimeType, stored.Size, stored.ScanStatus, uploader.UserID, uploader.Nama)
+	if err != nil {
+		return models.Attachment{}, err
+	}
+	id, _ := result.LastInsertId()
+
+	if stored.ScanStatus == ScanRejected {
+		if err := RecordTicketEvent(tx, ticketID, EventAttachmentRejected, uploader, "", originalName); err != nil {
+			return models.Attachment{}, err
+		}
+		var ticketNumber, subject string
+		tx.QueryRow(`SELECT ticket_number, subject FROM helpdesk_tickets WHERE id = ?`, ticketID).Scan(&ticketNumber, &subject)
+		if err := NotifyMalwareDetected(tx, ticketID, ticketNumber, subject, originalName, stored.Signature, uploader.Nama); err != nil {
+			return models.Attachment{}, err
+		}
+	} else if column, ok := BuktiColumns[kind]; ok {
+		var oldPath *string
+		tx.QueryRow(`SELECT `+column+` FROM helpdesk_tickets WHERE id = ? FOR UPDATE`, ticketID).Scan(&oldPath)
+		thumbColumn := BuktiThumbColumns[kind]
+		if _, err := tx.Exec(`UPDATE helpdesk_tickets SET `+column+` = ?, `+thumbColumn+` = ? WHERE id = ?`, stored.Path, stored.ThumbPath, ticketID); err != nil {
+			return models.Attachment{}, err
+		}
+
+		event := EventBuktiMasalah
+		if kind == AttachmentSelesai {
+			event = EventBuktiSelesai
+		}
+		old := ""
+		if oldPath != nil {
+			old = *oldPath
+		}
+		if err := RecordTicketEvent(tx, ticketID, event, uploader, old, stored.Path); err != nil {
+			return models.Attachment{}, err
+		}
+	} else if err := RecordTicketEvent(tx, ticketID, EventAttachmentAdded, uploader, "", originalName); err != nil {
+		return models.Attachment{}, err
+	}
+
+	if onRecord != nil {
+		if err := onRecord(tx, int(id)); err != nil {
+			return models.Attachment{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Attachment{}, err
+	}
+	return GetAttachment(ticketID, int(id), true)
+}
+
+// attachmentColumns - Columns selected for models.Attachment, in the order read by scanAttachment
+const attachmentColumns = `a.id, a.ticket_id, a.comment_id, a.kind, a.original_name, a.stored_path, a.thumbnail_path, a.mime_type, a.size,
+	a.scan_status, a.uploader_id, a.uploader_nama, a.created_at`
+
+func scanAttachment(row fieldScanner) (models.Attachment, error) {
+	var a models.Attachment
+	err := row.Scan(&a.ID, &a.TicketID, &a.CommentID, &a.Kind, &a.OriginalName, &a.StoredPath, &a.ThumbnailPath, &a.MimeType, &a.Size,
+		&a.ScanStatus, &a.UploaderID, &a.UploaderNama, &a.CreatedAt)
+	if err != nil {
+		return a, err
+	}
+	if a.ScanStatus == ScanRejected {
+		return a, nil // quarantined, never served
+	}
+	a.URL = SignAttachmentURL(a.TicketID, strconv.Itoa(a.ID))
+	if a.ThumbnailPath != nil {
+		url := SignThumbnailURL(a.TicketID, strconv.Itoa(a.ID))
+		a.ThumbnailURL = &url
+	}
+	return a, nil
+}
+
+// Attachments of internal comments and rejected files are only visible to staff
+const attachmentVisibility = ` AND a.scan_status <> 'rejected' AND (a.comment_id IS NULL OR NOT EXISTS (
+	SELECT 1 FROM helpdesk_ticket_comments cm WHERE cm.id = a.comment_id AND cm.is_internal = 1))`
+
+// ListAttachments returns the attachments of a ticket, oldest first
+func ListAttachments(ticketID int, includeInternal bool) ([]models.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM helpdesk_attachments a WHERE a.ticket_id = ?`
+	if !includeInternal {
+		query += attachmentVisibility
+	}
+	query += " ORDER BY a.created_at, a.id"
+
+	rows, err := config.DB.Query(query, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []models.Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, nil
+}
+
+// GetAttachment returns a single attachment of a ticket
+func GetAttachment(ticketID, attachmentID int, includeInternal bool) (models.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM helpdesk_attachments a WHERE a.ticket_id = ? AND a.id = ?`
+	if !includeInternal {
+		query += attachmentVisibility
+	}
+
+	a, err := scanAttachment(config.DB.QueryRow(query, ticketID, attachmentID))
+	if err == sql.ErrNoRows {
+		return a, ErrAttachmentNotFound
+	}
+	return a, err
+}
+
+// DeleteAttachment removes an attachment and its file. A deleted bukti falls back to
+// the previous file of the same kind.
+func DeleteAttachment(att models.Attachment, actor Actor) error {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM helpdesk_attachments WHERE id = ?`, att.ID); err != nil {
+		return err
+	}
+
+	if column, ok := BuktiColumns[att.Kind]; ok {
+		var previous, previousThumb *string
+		tx.QueryRow(`
+			SELECT stored_path, thumbnail_path FROM helpdesk_attachments
+			WHERE ticket_id = ? AND kind = ? AND scan_status <> 'rejected' ORDER BY created_at DESC, id DESC LIMIT 1
+		`, att.TicketID, att.Kind).Scan(&previous, &previousThumb)
+		thumbColumn := BuktiThumbColumns[att.Kind]
+		if _, err := tx.Exec(`
+			UPDATE helpdesk_tickets SET `+column+` = ?, `+thumbColumn+` = ? WHERE id = ? AND `+column+` = ?
+		`, previous, previousThumb, att.TicketID, att.StoredPath); err != nil {
+			return err
+		}
+	}
+
+	if err := RecordTicketEvent(tx, att.TicketID, EventAttachmentDeleted, actor, att.OriginalName, ""); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if err := FileStorage.Delete(att.StoredPath); err != nil {
+		log.Println("Failed to delete attachment file:", err)
+	}
+	if att.ThumbnailPath != nil {
+		if err := FileStorage.Delete(*att.ThumbnailPath); err != nil {
+			log.Println("Failed to delete attachment thumbnail:", err)
+		}
+	}
+	return nil
+}
+
+// randomUploadPath builds an unguessable storage path for a new upload of a ticket
+func randomUploadPath(ticketID int, ext string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("tickets/%d/%s%s", ticketID, hex.EncodeToString(buf), ext), nil
+}
+
+func writeUpload(file UploadedFile, storedPath, contentType string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	return FileStorage.Put(storedPath, src, file.Size, contentType)
+}
+
+// writeImageUpload stores the processed version of an image upload and its thumbnail
+// (stored next to it as <name>_thumb.jpg). Returns the stored size and thumbnail path.
+// The whole image is read into memory; ValidateUpload keeps it within maxImageSize.
+func writeImageUpload(file UploadedFile, storedPath, contentType string) (int64, *string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return 0, nil, err
+	}
+	data, err := io.ReadAll(src)
+	src.Close()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	img, err := ProcessImage(data, contentType)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if err := FileStorage.Put(storedPath, bytes.NewReader(img.Data), int64(len(img.Data)), contentType); err != nil {
+		return 0, nil, err
+	}
+	thumbPath := strings.TrimSuffix(storedPath, path.Ext(storedPath)) + "_thumb.jpg"
+	if err := FileStorage.Put(thumbPath, bytes.NewReader(img.Thumbnail), int64(len(img.Thumbnail)), "image/jpeg"); err != nil {
+		FileStorage.Delete(storedPath)
+		return 0, nil, err
+	}
+	return int64(len(img.Data)), &thumbPath, nil
+}
+
+// attachmentURLTTL is how long a signed attachment URL stays valid
+func attachmentURLTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("ATTACHMENT_URL_TTL"))
+	if err != nil || ttl <= 0 {
+		return 15 * time.Minute
+	}
+	return ttl
+}
+
+func attachmentSigningKey() []byte {
+	key := os.Getenv("ATTACHMENT_SIGNING_KEY")
+	if key == "" {
+		key = os.Getenv("JWT_SECRET")
+	}
+	if key == "" {
+		key = "default-secret-key-change-this"
+	}
+	return []byte(key)
+}
+
+func attachmentSignature(ticketID int, attachmentID string, expires int64) string {
+	mac := hmac.New(sha256.New, attachmentSigningKey())
+	fmt.Fprintf(mac, "%d/%s/%d", ticketID, attachmentID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignAttachmentURL returns a short-lived URL that serves an attachment without a JWT,
+// e.g. for <img> tags in the frontend
+func SignAttachmentURL(ticketID int, attachmentID string) string {
+	expires := time.Now().Add(attachmentURLTTL()).Unix()
+	return fmt.Sprintf("/api/files/tickets/%d/attachments/%s?expires=%d&signature=%s",
+		ticketID, attachmentID, expires, attachmentSignature(ticketID, attachmentID, expires))
+}
+
+// SignThumbnailURL returns a short-lived URL that serves an attachment's thumbnail
+func SignThumbnailURL(ticketID int, attachmentID string) string {
+	expires := time.Now().Add(attachmentURLTTL()).Unix()
+	return fmt.Sprintf("/api/files/tickets/%d/attachments/%s/thumbnail?expires=%d&signature=%s",
+		ticketID, attachmentID, expires, attachmentSignature(ticketID, attachmentID+"/thumbnail", expires))
+}
+
+// VerifyAttachmentSignature checks a signed attachment URL and its expiry
+func VerifyAttachmentSignature(ticketID int, attachmentID, expires, signature string) bool {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return false
+	}
+	expected := attachmentSignature(ticketID, attachmentID, exp)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"helpdesk-backend/config"
+)
+
+var ErrCategoryNotFound = errors.New("Kategori tidak ditemukan")
+
+// CategoryLineage returns the IDs of a category and its ancestors, nearest first.
+// SLA policies, routing rules and skills set on a parent category also apply to its
+// subcategories, like CategoryTeamID.
+func CategoryLineage(categoryID int) ([]int, error) {
+	ids := []int{}
+	seen := map[int]bool{}
+	current := &categoryID
+	for current != nil && !seen[*current] {
+		seen[*current] = true
+
+		var parentID *int
+		err := config.DB.QueryRow(`
+			SELECT parent_id FROM helpdesk_categories WHERE id = ?
+		`, *current).Scan(&parentID)
+		if err == sql.ErrNoRows {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, *current)
+		current = parentID
+	}
+	return ids, nil
+}
+
+// ticketCategoryLineage returns the category lineage of a ticket; tickets from before
+// the category tree may have no category ID, and then only match rules for any category
+func ticketCategoryLineage(categoryID *int) ([]int, error) {
+	if categoryID == nil {
+		return []int{}, nil
+	}
+	return CategoryLineage(*categoryID)
+}
+
+// CategoryName returns the name of a category, ErrCategoryNotFound if it does not exist
+func CategoryName(categoryID int) (string, error) {
+	var name string
+	err := config.DB.QueryRow(`SELECT name FROM helpdesk_categories WHERE id = ?`, categoryID).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", ErrCategoryNotFound
+	}
+	return name, err
+}
+
+// nearestCategoryOrder builds an "IN (...)" filter and a "FIELD(...)" ordering that
+// prefers the nearest category of a lineage
+func nearestCategoryOrder(column string, lineage []int) (in string, order string, args []interface{}) {
+	if len(lineage) == 0 {
+		return "FALSE", "0", nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(lineage)), ", ")
+	for _, id := range lineage {
+		args = append(args, id)
+	}
+	return column + " IN (" + placeholders + ")", "FIELD(" + column + ", " + placeholders + ")", args
+}
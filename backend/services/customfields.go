@@ -0,0 +1,269 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"helpdesk-backend/config"
+	"helpdesk-backend/models"
+)
+
+// Custom field types
+const (
+	FieldText    = "text"
+	FieldNumber  = "number"
+	FieldSelect  = "select"
+	FieldDate    = "date"
+	FieldBoolean = "boolean"
+)
+
+var fieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// FieldValidationError describes an invalid custom field definition or value
+type FieldValidationError struct {
+	Message string
+}
+
+func (e *FieldValidationError) Error() string {
+	return e.Message
+}
+
+func fieldError(format string, args ...interface{}) error {
+	return &FieldValidationError{Message: fmt.Sprintf(format, args...)}
+}
+
+// ValidateFieldDefinition checks a custom field definition made by an admin
+func ValidateFieldDefinition(req models.CategoryFieldRequest) error {
+	if !fieldKeyPattern.MatchString(req.Key) {
+		return fieldError("Key field hanya boleh berisi huruf kecil, angka dan garis bawah")
+	}
+	switch req.Type {
+	case FieldText, FieldNumber, FieldDate, FieldBoolean:
+	case FieldSelect:
+		if len(req.Options) == 0 {
+			return fieldError("Field select harus memiliki pilihan")
+		}
+	default:
+		return fieldError("Tipe field tidak valid: %s", req.Type)
+	}
+	return nil
+}
+
+// CategoryFields returns the custom fields of a category, including those
+// inherited from its parent categories
+func CategoryFields(categoryID int, onlyActive bool) ([]models.CategoryField, error) {
+	// Collect the category and its ancestors, root first
+	ids := []int{}
+	seen := map[int]bool{}
+	current := &categoryID
+	for current != nil && !seen[*current] {
+		seen[*current] = true
+		ids = append([]int{*current}, ids...)
+
+		var parentID *int
+		config.DB.QueryRow(`SELECT parent_id FROM helpdesk_categories WHERE id = ?`, *current).Scan(&parentID)
+		current = parentID
+	}
+
+	fields := []models.CategoryField{}
+	for _, id := range ids {
+		query := `
+			SELECT id, category_id, field_key, label, field_type, options, is_required, sort_order, is_active
+			FROM helpdesk_category_fields WHERE category_id = ?`
+		if onlyActive {
+			query += " AND is_active = 1"
+		}
+		query += " ORDER BY sort_order, id"
+
+		rows, err := config.DB.Query(query, id)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			f, err := scanCategoryField(rows)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			fields = append(fields, f)
+		}
+		rows.Close()
+	}
+	return fields, nil
+}
+
+// GetCategoryField returns a single custom field definition
+func GetCategoryField(fieldID int) (models.CategoryField, error) {
+	return scanCategoryField(config.DB.QueryRow(`
+		SELECT id, category_id, field_key, label, field_type, options, is_required, sort_order, is_active
+		FROM helpdesk_category_fields WHERE id = ?
+	`, fieldID))
+}
+
+type fieldScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCategoryField(row fieldScanner) (models.CategoryField, error) {
+	var f models.CategoryField
+	var options *string
+	err := row.Scan(&f.ID, &f.CategoryID, &f.Key, &f.Label, &f.Type, &options, &f.IsRequired, &f.SortOrder, &f.IsActive)
+	if err != nil {
+		return f, err
+	}
+	f.Options = []string{}
+	if options != nil {
+		json.Unmarshal([]byte(*options), &f.Options)
+	}
+	return f, nil
+}
+
+// EncodeFieldOptions serializes select options for storage
+func EncodeFieldOptions(options []string) *string {
+	if len(options) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(options)
+	s := string(data)
+	return &s
+}
+
+// ValidateCustomFields checks submitted values against the field definitions and
+// returns them normalized as strings keyed by field ID
+func ValidateCustomFields(fields []models.CategoryField, values map[string]interface{}) (map[int]string, error) {
+	known := map[string]bool{}
+	result := map[int]string{}
+
+	for _, f := range fields {
+		known[f.Key] = true
+
+		raw, ok := values[f.Key]
+		if !ok || raw == nil || raw == "" {
+			if f.IsRequired {
+				return nil, fieldError("Field %s wajib diisi", f.Label)
+			}
+			continue
+		}
+
+		value, err := normalizeFieldValue(f, raw)
+		if err != nil {
+			return nil, err
+		}
+		result[f.ID] = value
+	}
+
+	for key := range values {
+		if !known[key] {
+			return nil, fieldError("Field tidak dikenal: %s", key)
+		}
+	}
+	return result, nil
+}
+
+func normalizeFieldValue(f models.CategoryField, raw interface{}) (string, error) {
+	switch f.Type {
+	case FieldNumber:
+		switch v := raw.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case string:
+			if n, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return strconv.FormatFloat(n, 'f', -1, 64), nil
+			}
+		}
+		return "", fieldError("Field %s harus berupa angka", f.Label)
+
+	case FieldBoolean:
+		switch v := raw.(type) {
+		case bool:
+			return strconv.FormatBool(v), nil
+		case string:
+			if b, err := strconv.ParseBool(v); err == nil {
+				return strconv.FormatBool(b), nil
+			}
+		}
+		return "", fieldError("Field %s harus berupa ya/tidak", f.Label)
+
+	case FieldDate:
+		if v, ok := raw.(string); ok {
+			if _, err := time.Parse("2006-01-02", v); err == nil {
+				return v, nil
+			}
+		}
+		return "", fieldError("Field %s harus berupa tanggal (YYYY-MM-DD)", f.Label)
+
+	case FieldSelect:
+		if v, ok := raw.(string); ok {
+			for _, option := range f.Options {
+				if v == option {
+					return v, nil
+				}
+			}
+		}
+		return "", fieldError("Pilihan untuk field %s tidak valid", f.Label)
+
+	default:
+		if v, ok := raw.(string); ok {
+			return v, nil
+		}
+		return "", fieldError("Field %s harus berupa teks", f.Label)
+	}
+}
+
+// SaveTicketFieldValues stores the custom field values of a ticket
+func SaveTicketFieldValues(db Execer, ticketID int, values map[int]string) error {
+	for fieldID, value := range values {
+		if _, err := db.Exec(`
+			INSERT INTO helpdesk_ticket_field_values (ticket_id, field_id, value) VALUES (?, ?, ?)
+			ON DUPLICATE KEY UPDATE value = VALUES(value)
+		`, ticketID, fieldID, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadTicketFieldValues attaches custom field values to a list of tickets
+func LoadTicketFieldValues(tickets []models.Ticket) error {
+	if len(tickets) == 0 {
+		return nil
+	}
+
+	index := map[int]int{}
+	placeholders := make([]string, len(tickets))
+	args := make([]interface{}, len(tickets))
+	for i := range tickets {
+		tickets[i].CustomFields = []models.TicketFieldValue{}
+		index[tickets[i].ID] = i
+		placeholders[i] = "?"
+		args[i] = tickets[i].ID
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT v.ticket_id, f.field_key, f.label, f.field_type, v.value
+		FROM helpdesk_ticket_field_values v
+		JOIN helpdesk_category_fields f ON f.id = v.field_id
+		WHERE v.ticket_id IN (`+strings.Join(placeholders, ",")+`)
+		ORDER BY f.sort_order, f.id
+	`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ticketID int
+		var v models.TicketFieldValue
+		if err := rows.Scan(&ticketID, &v.Key, &v.Label, &v.Type, &v.Value); err != nil {
+			return err
+		}
+		if i, ok := index[ticketID]; ok {
+			tickets[i].CustomFields = append(tickets[i].CustomFields, v)
+		}
+	}
+	return nil
+}
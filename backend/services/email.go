@@ -0,0 +1,90 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// EmailConfig configures the SMTP notification channel
+type EmailConfig struct {
+	Host     string
+	Port     string
+	Username string // optional; without it mail is sent unauthenticated (e.g. MailHog)
+	Password string
+	From     string
+	To       []string
+}
+
+// EmailConfigFromEnv reads SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD, SMTP_FROM and SMTP_TO
+func EmailConfigFromEnv() EmailConfig {
+	return EmailConfig{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     os.Getenv("SMTP_PORT"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+		To:       splitList(os.Getenv("SMTP_TO")),
+	}
+}
+
+// emailNotifier sends notifications as plain text mail. SMTP_TO is an outside mailbox,
+// so internal notifications (internal notes, malware alerts) are not sent.
+type emailNotifier struct {
+	cfg EmailConfig
+}
+
+// NewEmailNotifier returns the SMTP notification channel
+func NewEmailNotifier(cfg EmailConfig) (Notifier, error) {
+	if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+		return nil, errors.New("SMTP_HOST, SMTP_FROM and SMTP_TO are required")
+	}
+	if cfg.Port == "" {
+		cfg.Port = "587"
+	}
+	return &emailNotifier{cfg: cfg}, nil
+}
+
+func (e *emailNotifier) Name() string { return "email" }
+
+func (e *emailNotifier) Notify(n Notification) error {
+	if n.Internal {
+		return nil
+	}
+	var auth smtp.Auth
+	if e.cfg.Username != "" {
+		auth = smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.cfg.Host)
+	}
+	return smtp.SendMail(net.JoinHostPort(e.cfg.Host, e.cfg.Port), auth, e.cfg.From, e.cfg.To, e.message(n))
+}
+
+func (e *emailNotifier) message(n Notification) []byte {
+	subject := "[Helpdesk] " + n.Title
+	if n.TicketNumber != "" {
+		subject += " " + n.TicketNumber
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", n.Time.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+
+	msg.WriteString(n.Title + "\r\n\r\n")
+	for _, f := range n.Fields {
+		fmt.Fprintf(&msg, "%s: %s\r\n", f.Label, f.Value)
+	}
+	if n.Body != "" {
+		msg.WriteString("\r\n" + strings.ReplaceAll(n.Body, "\n", "\r\n") + "\r\n")
+	}
+	return msg.Bytes()
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testNotification() Notification {
+	return Notification{
+		Event:        NotificationTicketCreated,
+		TicketID:     7,
+		TicketNumber: "HD-2026-0007",
+		Icon:         "🎫",
+		Title:        "Tiket Baru — Jaringan",
+		Fields: []NotificationField{
+			{"📋", "No", "HD-2026-0007"},
+			{"👤", "Pelapor", "Siti Aminah"},
+		},
+		Body: "Wi-Fi lantai 3 mati.\nSudah dicoba restart.",
+		Time: time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC),
+	}
+}
+
+func TestEmailMessage(t *testing.T) {
+	n, err := NewEmailNotifier(EmailConfig{Host: "mail.example.com", From: "helpdesk@example.com",
+		To: []string{"it@example.com", "ops@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := n.(*emailNotifier).message(testNotification())
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers := map[string]string{
+		"From":                      "helpdesk@example.com",
+		"To":                        "it@example.com, ops@example.com",
+		"Date":                      "Sat, 17 Oct 2026 09:30:00 +0000",
+		"MIME-Version":              "1.0",
+		"Content-Type":              "text/plain; charset=utf-8",
+		"Content-Transfer-Encoding": "8bit",
+	}
+	for name, want := range headers {
+		if got := msg.Header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if subject != "[Helpdesk] Tiket Baru — Jaringan HD-2026-0007" {
+		t.Errorf("Subject = %q", subject)
+	}
+	// Non-ASCII subjects must be encoded words, not raw UTF-8
+	if raw := msg.Header.Get("Subject"); !strings.HasPrefix(raw, "=?utf-8?q?") {
+		t.Errorf("raw Subject %q is not Q-encoded", raw)
+	}
+
+	body, _ := io.ReadAll(msg.Body)
+	want := "Tiket Baru — Jaringan\r\n\r\nNo: HD-2026-0007\r\nPelapor: Siti Aminah\r\n\r\n" +
+		"Wi-Fi lantai 3 mati.\r\nSudah dicoba restart.\r\n"
+	if string(body) != want {
+		t.Errorf("body %q, want %q", body, want)
+	}
+}
+
+func TestNewEmailNotifierConfig(t *testing.T) {
+	if _, err := NewEmailNotifier(EmailConfig{Host: "mail.example.com", From: "helpdesk@example.com"}); err == nil {
+		t.Error("accepted a config without recipients")
+	}
+	n, err := NewEmailNotifier(EmailConfig{Host: "mail.example.com", From: "a@example.com", To: []string{"b@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port := n.(*emailNotifier).cfg.Port; port != "587" {
+		t.Errorf("default port %q", port)
+	}
+}
+
+// smtpSession is what the fake SMTP server received
+type smtpSession struct {
+	auth       string
+	from       string
+	recipients []string
+	data       string
+}
+
+// fakeSMTP serves SMTP sessions on a local port, advertising AUTH PLAIN, and reports
+// each session on the returned channel
+func fakeSMTP(t *testing.T) (string, string, <-chan smtpSession) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	sessions := make(chan smtpSession, 4)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, sessions)
+		}
+	}()
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	return host, port, sessions
+}
+
+func serveSMTP(conn net.Conn, sessions chan<- smtpSession) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	var s smtpSession
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			s.auth = line
+			reply("235 2.7.0 Authentication successful")
+		case "MAIL":
+			s.from = line
+			reply("250 OK")
+		case "RCPT":
+			s.recipients = append(s.recipients, line)
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.data = data.String()
+			reply("250 OK queued")
+		case "QUIT":
+			reply("221 Bye")
+			sessions <- s
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestEmailNotifierSend(t *testing.T) {
+	host, port, sessions := fakeSMTP(t)
+	n, err := NewEmailNotifier(EmailConfig{Host: host, Port: port, Username: "helpdesk", Password: "rahasia",
+		From: "helpdesk@example.com", To: []string{"it@example.com", "ops@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := n.Notify(testNotification()); err != nil {
+		t.Fatal("Notify:", err)
+	}
+	s := <-sessions
+
+	wantAuth := "AUTH PLAIN " + base64.StdEncoding.EncodeToString([]byte("\x00helpdesk\x00rahasia"))
+	if s.auth != wantAuth {
+		t.Errorf("auth %q, want %q", s.auth, wantAuth)
+	}
+	if s.from != "MAIL FROM:<helpdesk@example.com> BODY=8BITMIME" && s.from != "MAIL FROM:<helpdesk@example.com>" {
+		t.Errorf("sender %q", s.from)
+	}
+	if strings.Join(s.recipients, "|") != "RCPT TO:<it@example.com>|RCPT TO:<ops@example.com>" {
+		t.Errorf("recipients %q", s.recipients)
+	}
+	if !strings.Contains(s.data, "\r\nWi-Fi lantai 3 mati.\r\nSudah dicoba restart.\r\n") {
+		t.Errorf("data %q", s.data)
+	}
+}
+
+func TestEmailNotifierSkipsInternal(t *testing.T) {
+	host, port, sessions := fakeSMTP(t)
+	n, err := NewEmailNotifier(EmailConfig{Host: host, Port: port, From: "helpdesk@example.com", To: []string{"it@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	internal := testNotification()
+	internal.Internal = true
+	if err := n.Notify(internal); err != nil {
+		t.Fatal("Notify:", err)
+	}
+	select {
+	case s := <-sessions:
+		t.Errorf("internal notification was mailed: %q", s.data)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestEmailNotifierSMTPError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.WriteString(conn, "554 No SMTP service here\r\n")
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	n, _ := NewEmailNotifier(EmailConfig{Host: host, Port: port, From: "helpdesk@example.com", To: []string{"it@example.com"}})
+	if err := n.Notify(testNotification()); err == nil || !strings.Contains(err.Error(), "554") {
+		t.Errorf("Notify error = %v, want the 554 reply", err)
+	}
+}
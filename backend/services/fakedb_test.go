@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"helpdesk-backend/config"
+)
+
+// fakeDB is a database/sql driver for tests. A query is answered by the first
+// fakeQuery whose match is part of the SQL; any other query fails. Execs succeed and
+// are recorded, and transactions are accepted without isolating anything.
+type fakeDB struct {
+	queries []fakeQuery
+
+	mu    sync.Mutex
+	execs []fakeExec
+}
+
+type fakeQuery struct {
+	match   string
+	columns []string
+	rows    func(args []driver.Value) [][]driver.Value
+}
+
+type fakeExec struct {
+	query string
+	args  []driver.Value
+}
+
+// useFakeDB makes config.DB answer with queries for the rest of the test
+func useFakeDB(t *testing.T, queries ...fakeQuery) *fakeDB {
+	db := &fakeDB{queries: queries}
+	previous := config.DB
+	config.DB = sql.OpenDB(db)
+	t.Cleanup(func() {
+		config.DB.Close()
+		config.DB = previous
+	})
+	return db
+}
+
+// execsMatching returns the recorded execs whose SQL contains match
+func (d *fakeDB) execsMatching(match string) []fakeExec {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	found := []fakeExec{}
+	for _, e := range d.execs {
+		if strings.Contains(e.query, match) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+func (d *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{d}, nil }
+func (d *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.db, query}, nil }
+func (fakeConn) Close() error                                { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                   { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.mu.Lock()
+	s.db.execs = append(s.db.execs, fakeExec{s.query, args})
+	s.db.mu.Unlock()
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	for _, q := range s.db.queries {
+		if strings.Contains(s.query, q.match) {
+			return &fakeRows{columns: q.columns, rows: q.rows(args)}, nil
+		}
+	}
+	return nil, fmt.Errorf("fake db: unexpected query: %s", s.query)
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (*fakeRows) Close() error        { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"database/sql"
+)
+
+// Ticket event types recorded in helpdesk_ticket_events
+const (
+	EventCreated            = "created"
+	EventStatusChanged      = "status_changed"
+	EventAssigned           = "assigned"
+	EventPriorityChanged    = "priority_changed"
+	EventBuktiMasalah       = "bukti_masalah_uploaded"
+	EventBuktiSelesai       = "bukti_selesai_uploaded"
+	EventAttachmentAdded    = "attachment_added"
+	EventAttachmentDeleted  = "attachment_deleted"
+	EventAttachmentRejected = "attachment_rejected"
+)
+
+// Execer is satisfied by both *sql.DB and *sql.Tx
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// RecordTicketEvent stores a ticket mutation in the audit trail
+func RecordTicketEvent(db Execer, ticketID int, eventType string, actor Actor, oldValue, newValue string) error {
+	return RecordTicketEventNote(db, ticketID, eventType, actor, oldValue, newValue, "")
+}
+
+// RecordTicketEventNote stores a ticket mutation together with a free-text note (e.g. a reason)
+func RecordTicketEventNote(db Execer, ticketID int, eventType string, actor Actor, oldValue, newValue, note string) error {
+	_, err := db.Exec(`
+		INSERT INTO helpdesk_ticket_events (ticket_id, event_type, actor_id, actor_nama, old_value, new_value, note)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, ticketID, eventType, actor.UserID, actor.Nama, nullIfEmpty(oldValue), nullIfEmpty(newValue), nullIfEmpty(note))
+	return err
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
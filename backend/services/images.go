@@ -0,0 +1,284 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"strconv"
+)
+
+var ErrImageInvalid = errors.New("Gambar tidak dapat dibaca")
+
+// Images larger than this are rejected before decoding (decompression bombs)
+const maxImagePixels = 50_000_000
+
+// IsProcessableImage reports whether ProcessImage handles a content type. Other image
+// types are refused by ValidateUpload, since they would be stored with their metadata.
+func IsProcessableImage(mimeType string) bool {
+	return mimeType == "image/jpeg" || mimeType == "image/png" || mimeType == "image/gif"
+}
+
+// maxImageSize returns the size limit of images in bytes (IMAGE_MAX_SIZE_MB, default 20).
+// Images are decoded in memory, so this applies whatever the upload's own limit.
+func maxImageSize() int64 {
+	return int64(envInt("IMAGE_MAX_SIZE_MB", 20)) << 20
+}
+
+// ProcessedImage is a re-encoded image without metadata plus its thumbnail
+type ProcessedImage struct {
+	Data      []byte
+	Thumbnail []byte // always JPEG
+}
+
+func envInt(key string, def int) int {
+	n, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || n <= 0 {
+		return def
+	}
+	return n
+}
+
+// ProcessImage re-encodes a JPEG, PNG or GIF, which drops EXIF, comments and other
+// metadata, after applying the EXIF orientation and downscaling it to IMAGE_MAX_DIMENSION
+// (default 1920). JPEGs are re-encoded with IMAGE_JPEG_QUALITY (default 85); PNGs stay
+// PNG. GIFs keep their frames and size, see processGIF.
+// The thumbnail fits in THUMBNAIL_SIZE (default 320) pixels.
+func ProcessImage(data []byte, mimeType string) (ProcessedImage, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width*cfg.Height > maxImagePixels {
+		return ProcessedImage{}, ErrImageInvalid
+	}
+	if mimeType == "image/gif" {
+		return processGIF(data, cfg)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return ProcessedImage{}, ErrImageInvalid
+	}
+
+	if mimeType == "image/jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+	img = fitImage(img, envInt("IMAGE_MAX_DIMENSION", 1920))
+
+	var out bytes.Buffer
+	if mimeType == "image/png" {
+		enc := png.Encoder{CompressionLevel: png.BestCompression}
+		err = enc.Encode(&out, img)
+	} else {
+		err = jpeg.Encode(&out, img, &jpeg.Options{Quality: envInt("IMAGE_JPEG_QUALITY", 85)})
+	}
+	if err != nil {
+		return ProcessedImage{}, err
+	}
+
+	thumb, err := thumbnail(img)
+	if err != nil {
+		return ProcessedImage{}, err
+	}
+	return ProcessedImage{Data: out.Bytes(), Thumbnail: thumb}, nil
+}
+
+// processGIF re-encodes every frame of a GIF, dropping comment and application
+// extensions. Frames are not downscaled, as resizing an animation would need
+// recomputing each frame's palette and offsets; the pixel limit covers all frames.
+func processGIF(data []byte, cfg image.Config) (ProcessedImage, error) {
+	anim, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil || len(anim.Image) == 0 || len(anim.Image)*cfg.Width*cfg.Height > maxImagePixels {
+		return ProcessedImage{}, ErrImageInvalid
+	}
+
+	var out bytes.Buffer
+	if err := gif.EncodeAll(&out, &gif.GIF{
+		Image:           anim.Image,
+		Delay:           anim.Delay,
+		LoopCount:       anim.LoopCount,
+		Disposal:        anim.Disposal,
+		Config:          anim.Config,
+		BackgroundIndex: anim.BackgroundIndex,
+	}); err != nil {
+		return ProcessedImage{}, err
+	}
+
+	thumb, err := thumbnail(anim.Image[0])
+	if err != nil {
+		return ProcessedImage{}, err
+	}
+	return ProcessedImage{Data: out.Bytes(), Thumbnail: thumb}, nil
+}
+
+// thumbnail encodes a JPEG of img fitting in THUMBNAIL_SIZE; transparent areas become white
+func thumbnail(img image.Image) ([]byte, error) {
+	thumb := fitImage(img, envInt("THUMBNAIL_SIZE", 320))
+	flat := image.NewRGBA(thumb.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), thumb, thumb.Bounds().Min, draw.Over)
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, flat, &jpeg.Options{Quality: 75}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// fitImage downscales an image so its longest side is at most max pixels, using
+// area averaging. Smaller images are returned unchanged.
+func fitImage(src image.Image, max int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= max && h <= max {
+		return src
+	}
+
+	dw, dh := max, h*max/w
+	if h > w {
+		dw, dh = w*max/h, max
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	rgba := image.NewNRGBA(b)
+	draw.Draw(rgba, b, src, b.Min, draw.Src)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, (y+1)*h/dh
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, (x+1)*w/dw
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				i := sy*rgba.Stride + x0*4
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(rgba.Pix[i])
+					g += uint32(rgba.Pix[i+1])
+					bl += uint32(rgba.Pix[i+2])
+					a += uint32(rgba.Pix[i+3])
+					n++
+					i += 4
+				}
+			}
+
+			j := y*dst.Stride + x*4
+			dst.Pix[j] = uint8(r / n)
+			dst.Pix[j+1] = uint8(g / n)
+			dst.Pix[j+2] = uint8(bl / n)
+			dst.Pix[j+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// jpegOrientation reads the EXIF orientation (1-8) of a JPEG, or 1 if absent
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		size := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if marker == 0xDA || size < 2 || pos+2+size > len(data) {
+			return 1 // start of scan or malformed: no EXIF before the image data
+		}
+		segment := data[pos+4 : pos+2+size]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+		pos += 2 + size
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if o := int(order.Uint16(tiff[entry+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// applyOrientation rotates/flips an image so it displays upright without EXIF
+func applyOrientation(src image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // mirrored along the top-left diagonal
+				dx, dy = y, x
+			case 6: // rotated 90° clockwise
+				dx, dy = h-1-y, x
+			case 7: // mirrored along the top-right diagonal
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90° counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, src.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
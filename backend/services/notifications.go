@@ -0,0 +1,232 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The Notify* functions queue notifications in the outbox. Pass the transaction that
+// makes the change, so the notification is only sent if the change is committed.
+
+// NotifyNewTicket queues a notification for a new ticket
+func NotifyNewTicket(db Execer, ticketID int, ticketNumber, subject, category, priority, userName string) error {
+	icon, title := "🆕", "Tiket Baru!"
+	if priority == LevelKritis {
+		icon, title = "🚨", "TIKET KRITIS!"
+	}
+
+	return EnqueueNotification(db, Notification{
+		Event:        NotificationTicketCreated,
+		TicketID:     ticketID,
+		TicketNumber: ticketNumber,
+		Icon:         icon,
+		Title:        title,
+		Fields: []NotificationField{
+			{"📋", "No", ticketNumber},
+			{"📝", "Subject", subject},
+			{"📁", "Kategori", category},
+			{"🔥", "Prioritas", strings.ToUpper(priority)},
+			{"👤", "Dari", userName},
+		},
+		Data: map[string]string{"category": category, "priority": priority, "requester": userName},
+	})
+}
+
+// NotifyStatusChange queues a notification for a status change
+func NotifyStatusChange(db Execer, ticketID int, ticketNumber, subject, oldStatus, newStatus, handledBy string) error {
+	statusEmoji := map[string]string{
+		"dikerjakan": "🔄",
+		"selesai":    "✅",
+		"ditutup":    "🔒",
+	}
+
+	emoji := statusEmoji[newStatus]
+	if emoji == "" {
+		emoji = "📋"
+	}
+
+	return EnqueueNotification(db, Notification{
+		Event:        NotificationTicketStatus,
+		TicketID:     ticketID,
+		TicketNumber: ticketNumber,
+		Icon:         emoji,
+		Title:        "Status Tiket Berubah!",
+		Fields: []NotificationField{
+			{"📋", "No", ticketNumber},
+			{"📝", "Subject", subject},
+			{"📊", "Status", oldStatus + " → " + newStatus},
+			{"👷", "Dikerjakan", handledBy},
+		},
+		Data: map[string]string{"old_status": oldStatus, "new_status": newStatus, "handled_by": handledBy},
+	})
+}
+
+// NotifyNewComment queues a notification for a new ticket comment
+func NotifyNewComment(db Execer, ticketID int, ticketNumber, subject, author, body string, internal bool) error {
+	icon, title := "💬", "Komentar Baru!"
+	if internal {
+		icon, title = "🔐", "Catatan Internal Baru!"
+	}
+
+	return EnqueueNotification(db, Notification{
+		Event:        NotificationTicketCommented,
+		TicketID:     ticketID,
+		TicketNumber: ticketNumber,
+		Icon:         icon,
+		Title:        title,
+		Fields: []NotificationField{
+			{"📋", "No", ticketNumber},
+			{"📝", "Subject", subject},
+			{"👤", "Dari", author},
+		},
+		Body:     body,
+		Internal: internal,
+		Data:     map[string]string{"author": author},
+	})
+}
+
+// NotifySLAWarning queues a notification for a ticket about to breach its SLA
+func NotifySLAWarning(db Execer, ticketID int, ticketNumber, subject, kind string, dueAt time.Time) error {
+	status := "akan melewati"
+	if time.Now().After(dueAt) {
+		status = "telah melewati"
+	}
+
+	return EnqueueNotification(db, Notification{
+		Event:        NotificationTicketSLA,
+		TicketID:     ticketID,
+		TicketNumber: ticketNumber,
+		Icon:         "⏰",
+		Title:        "Peringatan SLA!",
+		Fields: []NotificationField{
+			{"📋", "No", ticketNumber},
+			{"📝", "Subject", subject},
+		},
+		Body: fmt.Sprintf("⚠️ Tiket %s batas waktu %s pada %s", status, kind, dueAt.Format("02-01-2006 15:04")),
+		Data: map[string]string{"kind": kind, "due_at": dueAt.Format(time.RFC3339)},
+	})
+}
+
+// NotifyTicketAssigned queues a notification for a ticket assignment
+func NotifyTicketAssigned(db Execer, ticketID int, ticketNumber, subject, assigneeName, assignedBy, reason string) error {
+	fields := []NotificationField{
+		{"📋", "No", ticketNumber},
+		{"📝", "Subject", subject},
+		{"🧑‍🔧", "Teknisi", assigneeName},
+		{"👤", "Oleh", assignedBy},
+	}
+	if reason != "" {
+		fields = append(fields, NotificationField{"💬", "Alasan", reason})
+	}
+
+	return EnqueueNotification(db, Notification{
+		Event:        NotificationTicketAssigned,
+		TicketID:     ticketID,
+		TicketNumber: ticketNumber,
+		Icon:         "👷",
+		Title:        "Tiket Ditugaskan!",
+		Fields:       fields,
+		Data:         map[string]string{"assignee": assigneeName, "assigned_by": assignedBy, "reason": reason},
+	})
+}
+
+// NotifyMalwareDetected queues an admin alert about a quarantined upload
+func NotifyMalwareDetected(db Execer, ticketID int, ticketNumber, subject, filename, signature, uploaderName string) error {
+	return EnqueueNotification(db, Notification{
+		Event:        NotificationMalware,
+		TicketID:     ticketID,
+		TicketNumber: ticketNumber,
+		Icon:         "☣️",
+		Title:        "Malware Terdeteksi!",
+		Fields: []NotificationField{
+			{"📋", "No", ticketNumber},
+			{"📝", "Subject", subject},
+			{"📎", "File", filename},
+			{"🦠", "Deteksi", signature},
+			{"👤", "Pengunggah", uploaderName},
+		},
+		Body:       "File telah dikarantina dan tidak dapat diunduh.",
+		Internal:   true,
+		AdminAlert: true,
+		Data:       map[string]string{"filename": filename, "signature": signature, "uploader": uploaderName},
+	})
+}
+
+// notifyUser queues a personal notification for one user. Only users who linked a
+// Telegram chat receive them.
+func notifyUser(db Execer, userID string, n Notification) error {
+	if userID == "" || TelegramChatForUser(userID) == "" {
+		return nil
+	}
+	n.RecipientID = userID
+	return EnqueueNotification(db, n)
+}
+
+// NotifyRequesterStatus tells the requester that their ticket was picked up or resolved
+func NotifyRequesterStatus(db Execer, ticketID int, ticketNumber, subject, requesterID, newStatus, handledBy string) error {
+	var icon, title, body string
+	switch newStatus {
+	case StatusDikerjakan:
+		icon, title, body = "🔄", "Tiket Anda Sedang Dikerjakan", "Petugas sudah menangani tiket Anda."
+	case StatusSelesai:
+		icon, title, body = "✅", "Tiket Anda Telah Selesai", "Silakan periksa hasilnya. Jika masalah belum teratasi, buka kembali tiket melalui aplikasi Helpdesk."
+	default:
+		return nil
+	}
+
+	return notifyUser(db, requesterID, Notification{
+		Event:        NotificationTicketStatus,
+		TicketID:     ticketID,
+		TicketNumber: ticketNumber,
+		Icon:         icon,
+		Title:        title,
+		Fields: []NotificationField{
+			{"📋", "No", ticketNumber},
+			{"📝", "Subject", subject},
+			{"👷", "Petugas", handledBy},
+		},
+		Body: body,
+		Data: map[string]string{"new_status": newStatus, "handled_by": handledBy},
+	})
+}
+
+// NotifyRequesterComment tells the requester about a public reply on their ticket
+func NotifyRequesterComment(db Execer, ticketID int, ticketNumber, subject, requesterID, author, body string) error {
+	return notifyUser(db, requesterID, Notification{
+		Event:        NotificationTicketCommented,
+		TicketID:     ticketID,
+		TicketNumber: ticketNumber,
+		Icon:         "💬",
+		Title:        "Balasan Baru pada Tiket Anda",
+		Fields: []NotificationField{
+			{"📋", "No", ticketNumber},
+			{"📝", "Subject", subject},
+			{"👤", "Dari", author},
+		},
+		Body: body,
+		Data: map[string]string{"author": author},
+	})
+}
+
+// NotifyAssignee tells a staff member that a ticket was assigned to them
+func NotifyAssignee(db Execer, ticketID int, ticketNumber, subject, assigneeID, assignedBy, reason string) error {
+	fields := []NotificationField{
+		{"📋", "No", ticketNumber},
+		{"📝", "Subject", subject},
+		{"👤", "Oleh", assignedBy},
+	}
+	if reason != "" {
+		fields = append(fields, NotificationField{"💬", "Alasan", reason})
+	}
+
+	return notifyUser(db, assigneeID, Notification{
+		Event:        NotificationTicketAssigned,
+		TicketID:     ticketID,
+		TicketNumber: ticketNumber,
+		Icon:         "👷",
+		Title:        "Tiket Ditugaskan kepada Anda",
+		Fields:       fields,
+		Data:         map[string]string{"assigned_by": assignedBy, "reason": reason},
+	})
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Notification events (Notification.Event)
+const (
+	NotificationTicketCreated   = "ticket.created"
+	NotificationTicketStatus    = "ticket.status_changed"
+	NotificationTicketAssigned  = "ticket.assigned"
+	NotificationTicketCommented = "ticket.commented"
+	NotificationTicketSLA       = "ticket.sla_warning"
+	NotificationMalware         = "attachment.malware_detected"
+)
+
+// NotificationField is one labelled line of a notification
+type NotificationField struct {
+	Icon  string `json:"icon,omitempty"`
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// Notification is a channel-independent message about a helpdesk event. Channels
+// render Title, Fields and Body in their own format.
+type Notification struct {
+	Event        string              `json:"event"`
+	TicketID     int                 `json:"ticket_id,omitempty"`
+	TicketNumber string              `json:"ticket_number,omitempty"`
+	Icon         string              `json:"icon,omitempty"`
+	Title        string              `json:"title"`
+	Fields       []NotificationField `json:"fields"`
+	Body         string              `json:"body,omitempty"`
+	Internal     bool                `json:"internal"`               // staff-only content such as internal notes
+	AdminAlert   bool                `json:"admin_alert"`            // for helpdesk admins rather than the ticket's team
+	RecipientID  string              `json:"recipient_id,omitempty"` // user_id of a personal notification
+	Data         map[string]string   `json:"data,omitempty"`
+	Time         time.Time           `json:"time"`
+}
+
+// Notifier delivers notifications through one channel
+type Notifier interface {
+	Name() string
+	Notify(n Notification) error
+}
+
+// DeliveryError tells the outbox when, or whether, to retry a failed delivery
+type DeliveryError struct {
+	Err        error
+	RetryAfter time.Duration // wait requested by the receiver, e.g. Telegram's retry_after
+	Permanent  bool          // retrying cannot succeed
+}
+
+func (e *DeliveryError) Error() string { return e.Err.Error() }
+func (e *DeliveryError) Unwrap() error { return e.Err }
+
+// Dispatcher knows the configured channels and which events each one receives
+type Dispatcher struct {
+	channels []notificationChannel
+}
+
+type notificationChannel struct {
+	notifier Notifier
+	events   map[string]bool // nil means every event
+	personal bool            // receives personal notifications (RecipientID set) only
+}
+
+// Notifications holds the channels used by the outbox, set up by InitNotifiers
+var Notifications = &Dispatcher{}
+
+// Register adds a channel, optionally limited to some events
+func (d *Dispatcher) Register(n Notifier, events ...string) {
+	ch := notificationChannel{notifier: n}
+	if len(events) > 0 {
+		ch.events = map[string]bool{}
+		for _, e := range events {
+			ch.events[e] = true
+		}
+	}
+	d.channels = append(d.channels, ch)
+}
+
+// RegisterPersonal adds a channel that delivers notifications addressed to one user
+func (d *Dispatcher) RegisterPersonal(n Notifier) {
+	d.channels = append(d.channels, notificationChannel{notifier: n, personal: true})
+}
+
+// ChannelsFor returns the names of the channels that receive a notification
+func (d *Dispatcher) ChannelsFor(n Notification) []string {
+	names := []string{}
+	for _, ch := range d.channels {
+		if ch.personal != (n.RecipientID != "") {
+			continue
+		}
+		if ch.events == nil || ch.events[n.Event] {
+			names = append(names, ch.notifier.Name())
+		}
+	}
+	return names
+}
+
+// Notifier returns a channel by name
+func (d *Dispatcher) Notifier(name string) (Notifier, bool) {
+	for _, ch := range d.channels {
+		if ch.notifier.Name() == name {
+			return ch.notifier, true
+		}
+	}
+	return nil, false
+}
+
+// InitNotifiers registers the channels listed in NOTIFY_CHANNELS (default "telegram").
+// <CHANNEL>_EVENTS, e.g. WEBHOOK_EVENTS=ticket.created,ticket.status_changed, limits a
+// channel to some events.
+func InitNotifiers() {
+	d := &Dispatcher{}
+
+	channels := os.Getenv("NOTIFY_CHANNELS")
+	if channels == "" {
+		channels = "telegram"
+	}
+	for _, name := range strings.Split(channels, ",") {
+		var notifiers []Notifier
+		switch name = strings.TrimSpace(strings.ToLower(name)); name {
+		case "":
+			continue
+		case "telegram":
+			if os.Getenv("TELEGRAM_BOT_TOKEN") == "" || os.Getenv("TELEGRAM_CHAT_ID") == "" {
+				log.Println("Telegram notifications disabled: TELEGRAM_BOT_TOKEN or TELEGRAM_CHAT_ID not set")
+				continue
+			}
+			notifiers = []Notifier{NewTelegramNotifier()}
+		case "email":
+			n, err := NewEmailNotifier(EmailConfigFromEnv())
+			if err != nil {
+				log.Fatal("Invalid email notification configuration:", err)
+			}
+			notifiers = []Notifier{n}
+		case "webhook":
+			n, err := NewWebhookNotifiers(splitList(os.Getenv("WEBHOOK_URLS")), os.Getenv("WEBHOOK_SECRET"))
+			if err != nil {
+				log.Fatal("Invalid webhook notification configuration:", err)
+			}
+			notifiers = n
+		default:
+			log.Fatalf("Unknown notification channel %q", name)
+		}
+
+		for _, notifier := range notifiers {
+			d.Register(notifier, splitList(os.Getenv(strings.ToUpper(name)+"_EVENTS"))...)
+		}
+	}
+
+	// Requesters who linked their Telegram account get personal messages
+	if os.Getenv("TELEGRAM_BOT_TOKEN") != "" {
+		d.RegisterPersonal(NewTelegramPersonalNotifier())
+	}
+
+	Notifications = d
+}
+
+// splitList splits a comma separated setting, dropping empty entries
+func splitList(s string) []string {
+	items := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"helpdesk-backend/config"
+	"helpdesk-backend/models"
+)
+
+// Outbox delivery status
+const (
+	DeliveryPending = "pending"
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"
+)
+
+var (
+	ErrDeliveryNotFound  = errors.New("Notifikasi tidak ditemukan")
+	ErrDeliveryNotFailed = errors.New("Hanya notifikasi yang gagal yang dapat dikirim ulang")
+)
+
+// Retry schedule of failed deliveries
+const (
+	outboxBaseBackoff = 30 * time.Second
+	outboxMaxBackoff  = time.Hour
+	outboxBatchSize   = 50
+	// A claimed delivery is retried by another worker if this one dies while sending
+	outboxClaimTimeout = 5 * time.Minute
+)
+
+// EnqueueNotification stores a notification in the outbox, one delivery per channel
+// that receives the event
+func EnqueueNotification(db Execer, n Notification) error {
+	if n.Time.IsZero() {
+		n.Time = time.Now()
+	}
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	var ticketID *int
+	if n.TicketID != 0 {
+		ticketID = &n.TicketID
+	}
+	for _, channel := range Notifications.ChannelsFor(n) {
+		if _, err := db.Exec(`
+			INSERT INTO helpdesk_notification_outbox (channel, event, ticket_id, payload, status, next_attempt_at)
+			VALUES (?, ?, ?, ?, ?, NOW())
+		`, channel, n.Event, ticketID, payload, DeliveryPending); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maxDeliveryAttempts is how often a delivery is tried before it is marked failed
+// (NOTIFY_MAX_ATTEMPTS, default 8)
+func maxDeliveryAttempts() int {
+	n, err := strconv.Atoi(os.Getenv("NOTIFY_MAX_ATTEMPTS"))
+	if err != nil || n <= 0 {
+		return 8
+	}
+	return n
+}
+
+// StartNotificationWorker delivers queued notifications in the background
+// (every NOTIFY_WORKER_INTERVAL, default 5s)
+func StartNotificationWorker() {
+	interval, err := time.ParseDuration(os.Getenv("NOTIFY_WORKER_INTERVAL"))
+	if err != nil || interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := processOutbox(); err != nil {
+				log.Println("Notification delivery failed:", err)
+			}
+		}
+	}()
+}
+
+type outboxDelivery struct {
+	id       int64
+	channel  string
+	payload  []byte
+	attempts int
+}
+
+func processOutbox() error {
+	rows, err := config.DB.Query(`
+		SELECT id, channel, payload, attempts FROM helpdesk_notification_outbox
+		WHERE status = ? AND next_attempt_at <= NOW()
+		ORDER BY next_attempt_at, id LIMIT ?
+	`, DeliveryPending, outboxBatchSize)
+	if err != nil {
+		return err
+	}
+	due := []outboxDelivery{}
+	for rows.Next() {
+		var d outboxDelivery
+		if err := rows.Scan(&d.id, &d.channel, &d.payload, &d.attempts); err != nil {
+			rows.Close()
+			return err
+		}
+		due = append(due, d)
+	}
+	rows.Close()
+
+	// A channel that asked us to slow down is skipped for the rest of the batch
+	paused := map[string]bool{}
+	for _, d := range due {
+		if paused[d.channel] {
+			continue
+		}
+		claimed, err := claimDelivery(d.id)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		deliveryErr := deliver(d)
+		if err := recordDelivery(d, deliveryErr); err != nil {
+			return err
+		}
+
+		var de *DeliveryError
+		if errors.As(deliveryErr, &de) && de.RetryAfter > 0 {
+			paused[d.channel] = true
+		}
+	}
+
+	// Keep the table small; failed deliveries stay until retried
+	_, err = config.DB.Exec(`
+		DELETE FROM helpdesk_notification_outbox
+		WHERE status = ? AND sent_at < NOW() - INTERVAL 7 DAY LIMIT 500
+	`, DeliverySent)
+	return err
+}
+
+// claimDelivery pushes back next_attempt_at so other workers leave the delivery alone
+func claimDelivery(id int64) (bool, error) {
+	result, err := config.DB.Exec(`
+		UPDATE helpdesk_notification_outbox SET next_attempt_at = NOW() + INTERVAL ? SECOND
+		WHERE id = ? AND status = ? AND next_attempt_at <= NOW()
+	`, int(outboxClaimTimeout.Seconds()), id, DeliveryPending)
+	if err != nil {
+		return false, err
+	}
+	n, _ := result.RowsAffected()
+	return n == 1, nil
+}
+
+func deliver(d outboxDelivery) error {
+	notifier, ok := Notifications.Notifier(d.channel)
+	if !ok {
+		return &DeliveryError{Err: fmt.Errorf("channel %q is not configured", d.channel), Permanent: true}
+	}
+	var n Notification
+	if err := json.Unmarshal(d.payload, &n); err != nil {
+		return &DeliveryError{Err: err, Permanent: true}
+	}
+	return notifier.Notify(n)
+}
+
+// recordDelivery stores the outcome of an attempt and schedules the next one
+func recordDelivery(d outboxDelivery, deliveryErr error) error {
+	attempts := d.attempts + 1
+	if deliveryErr == nil {
+		_, err := config.DB.Exec(`
+			UPDATE helpdesk_notification_outbox SET status = ?, attempts = ?, sent_at = NOW() WHERE id = ?
+		`, DeliverySent, attempts, d.id)
+		return err
+	}
+
+	wait := retryBackoff(attempts)
+	status := DeliveryPending
+	var de *DeliveryError
+	if errors.As(deliveryErr, &de) {
+		if de.RetryAfter > 0 {
+			wait = de.RetryAfter
+		}
+		if de.Permanent {
+			status = DeliveryFailed
+		}
+	}
+	if attempts >= maxDeliveryAttempts() {
+		status = DeliveryFailed
+	}
+
+	_, err := config.DB.Exec(`
+		UPDATE helpdesk_notification_outbox
+		SET status = ?, attempts = ?, last_error = ?, next_attempt_at = NOW() + INTERVAL ? SECOND
+		WHERE id = ?
+	`, status, attempts, deliveryErr.Error(), int(wait.Seconds()), d.id)
+	return err
+}
+
+// retryBackoff doubles the wait after every failed attempt, up to outboxMaxBackoff
+func retryBackoff(attempts int) time.Duration {
+	wait := outboxBaseBackoff
+	for i := 1; i < attempts && wait < outboxMaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > outboxMaxBackoff {
+		wait = outboxMaxBackoff
+	}
+	return wait
+}
+
+// ListDeliveries returns outbox entries with a status, newest first
+func ListDeliveries(status string, limit int) ([]models.NotificationDelivery, error) {
+	rows, err := config.DB.Query(`
+		SELECT id, channel, event, ticket_id, payload, status, attempts, last_error, next_attempt_at, created_at, sent_at
+		FROM helpdesk_notification_outbox WHERE status = ?
+		ORDER BY created_at DESC, id DESC LIMIT ?
+	`, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.NotificationDelivery{}
+	for rows.Next() {
+		var d models.NotificationDelivery
+		var payload []byte
+		if err := rows.Scan(&d.ID, &d.Channel, &d.Event, &d.TicketID, &payload, &d.Status, &d.Attempts,
+			&d.LastError, &d.NextAttemptAt, &d.CreatedAt, &d.SentAt); err != nil {
+			return nil, err
+		}
+		d.Notification = json.RawMessage(payload)
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
+
+// RetryDelivery queues a failed delivery again with a fresh set of attempts
+func RetryDelivery(id int64) error {
+	result, err := config.DB.Exec(`
+		UPDATE helpdesk_notification_outbox SET status = ?, attempts = 0, next_attempt_at = NOW()
+		WHERE id = ? AND status = ?
+	`, DeliveryPending, id, DeliveryFailed)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 1 {
+		return nil
+	}
+
+	var exists int
+	err = config.DB.QueryRow(`SELECT 1 FROM helpdesk_notification_outbox WHERE id = ?`, id).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return ErrDeliveryNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return ErrDeliveryNotFailed
+}
@@ -0,0 +1,29 @@
+package services
+
+import "errors"
+
+// Urgency, impact and priority levels
+const (
+	LevelRendah = "rendah"
+	LevelSedang = "sedang"
+	LevelTinggi = "tinggi"
+	LevelKritis = "kritis"
+)
+
+var ErrInvalidLevel = errors.New("Urgensi dan dampak harus salah satu dari: rendah, sedang, tinggi")
+
+// priorityMatrix maps impact → urgency → priority
+var priorityMatrix = map[string]map[string]string{
+	LevelRendah: {LevelRendah: LevelRendah, LevelSedang: LevelRendah, LevelTinggi: LevelSedang},
+	LevelSedang: {LevelRendah: LevelRendah, LevelSedang: LevelSedang, LevelTinggi: LevelTinggi},
+	LevelTinggi: {LevelRendah: LevelSedang, LevelSedang: LevelTinggi, LevelTinggi: LevelKritis},
+}
+
+// ComputePriority derives the ticket priority from urgency and impact
+func ComputePriority(urgency, impact string) (string, error) {
+	priority, ok := priorityMatrix[impact][urgency]
+	if !ok {
+		return "", ErrInvalidLevel
+	}
+	return priority, nil
+}
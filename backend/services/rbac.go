@@ -0,0 +1,68 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+
+	"helpdesk-backend/config"
+)
+
+// Helpdesk staff roles stored in helpdesk_admins.role. Users without a row
+// there are requesters (RoleRequester).
+const (
+	RoleTechnician  = "technician"
+	RoleSupervisor  = "supervisor"
+	RoleUnitManager = "unit_manager"
+	RoleSuperAdmin  = "super_admin"
+)
+
+// Permissions checked by middleware.RequirePermission
+const (
+	PermTicketViewAll = "ticket:view_all" // see every ticket, internal notes and the staff list
+	PermTicketWork    = "ticket:work"     // work on tickets: status changes, self-assign, bukti selesai
+	PermTicketAssign  = "ticket:assign"   // dispatch tickets to others and set priority
+	PermReportView    = "report:view"     // dashboards and reports
+	PermConfigManage  = "config:manage"   // SLA, routing, teams, categories and custom fields
+	PermStaffManage   = "staff:manage"    // grant/revoke staff access and roles
+)
+
+var rolePermissions = map[string][]string{
+	RoleRequester:   {},
+	RoleTechnician:  {PermTicketViewAll, PermTicketWork},
+	RoleSupervisor:  {PermTicketViewAll, PermTicketWork, PermTicketAssign, PermReportView, PermConfigManage},
+	RoleUnitManager: {PermTicketViewAll, PermReportView},
+	RoleSuperAdmin:  {PermTicketViewAll, PermTicketWork, PermTicketAssign, PermReportView, PermConfigManage, PermStaffManage},
+}
+
+var ErrInvalidRole = errors.New("Role tidak valid")
+
+// IsValidStaffRole reports whether a role can be given to helpdesk staff
+func IsValidStaffRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok && role != RoleRequester
+}
+
+// UserRole returns the helpdesk role of a user
+func UserRole(userID string) (string, error) {
+	var role string
+	err := config.DB.QueryRow(`SELECT role FROM helpdesk_admins WHERE user_id = ?`, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return RoleRequester, nil
+	}
+	return role, err
+}
+
+// RoleHasPermission reports whether a role grants a permission
+func RoleHasPermission(role, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RolePermissions returns the permissions granted by a role
+func RolePermissions(role string) []string {
+	return append([]string{}, rolePermissions[role]...)
+}
@@ -0,0 +1,508 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"helpdesk-backend/config"
+	"helpdesk-backend/models"
+)
+
+// Resumable uploads: the client creates a session with the file's size and SHA-256, sends the
+// file in chunks with PATCH at the session's current offset (resuming from GET after a failure),
+// and the last chunk turns the assembled file into an attachment through SaveAttachment.
+//
+// Each chunk is stored as its own object in FileStorage and listed in helpdesk_upload_chunks,
+// so any instance can receive the next chunk. The session's received counter decides which of
+// two chunks sent for the same offset is kept; the other is deleted.
+
+var (
+	ErrUploadNotFound        = errors.New("Upload not found")
+	ErrUploadOffsetMismatch  = errors.New("Upload-Offset tidak sesuai dengan data yang sudah diterima")
+	ErrUploadChunkTooLarge   = errors.New("Chunk melebihi ukuran file")
+	ErrUploadInvalidChecksum = errors.New("Checksum harus berupa SHA-256 (hex)")
+	ErrUploadChecksum        = errors.New("Checksum file tidak sesuai, upload dibatalkan")
+	ErrUploadInProgress      = errors.New("Upload sedang diselesaikan, coba lagi nanti")
+)
+
+// Chunks of incomplete uploads are stored under this prefix
+const partialUploadPrefix = "partial/"
+
+// uploadChunkKey returns a new storage key for a chunk. Keys are random so a chunk that
+// loses a race for an offset never overwrites the one that won.
+func uploadChunkKey(sessionID string, offset int64) (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%s/%d-%s", partialUploadPrefix, sessionID, offset, hex.EncodeToString(buf)), nil
+}
+
+// maxResumableUploadSize returns the size limit of resumable uploads in bytes
+// (RESUMABLE_UPLOAD_MAX_SIZE_MB, default 500)
+func maxResumableUploadSize() int64 {
+	mb, err := strconv.Atoi(os.Getenv("RESUMABLE_UPLOAD_MAX_SIZE_MB"))
+	if err != nil || mb <= 0 {
+		mb = 500
+	}
+	return int64(mb) << 20
+}
+
+// uploadSessionTTL is how long an idle session is kept (RESUMABLE_UPLOAD_TTL, default 24h)
+func uploadSessionTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("RESUMABLE_UPLOAD_TTL"))
+	if err != nil || ttl <= 0 {
+		return 24 * time.Hour
+	}
+	return ttl
+}
+
+// CreateUploadSession starts a resumable upload for a ticket
+func CreateUploadSession(ticketID int, commentID *int, kind, filename string, size int64, checksum string, uploader Actor) (models.UploadSession, error) {
+	if !IsValidAttachmentKind(kind) {
+		return models.UploadSession{}, ErrInvalidAttachmentKind
+	}
+	if size <= 0 {
+		return models.UploadSession{}, ErrFileEmpty
+	}
+	if max := maxResumableUploadSize(); size > max {
+		return models.UploadSession{}, fmt.Errorf("%w (maksimal %d MB)", ErrFileTooLarge, max>>20)
+	}
+	checksum = strings.ToLower(strings.TrimSpace(checksum))
+	if sum, err := hex.DecodeString(checksum); err != nil || len(sum) != sha256.Size {
+		return models.UploadSession{}, ErrUploadInvalidChecksum
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return models.UploadSession{}, err
+	}
+	id := hex.EncodeToString(buf)
+
+	_, err := config.DB.Exec(`
+		INSERT INTO helpdesk_upload_sessions (id, ticket_id, comment_id, kind, filename, size, checksum, uploader_id, uploader_nama, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, id, ticketID, commentID, kind, filename, size, checksum, uploader.UserID, uploader.Nama, time.Now().Add(uploadSessionTTL()))
+	if err != nil {
+		return models.UploadSession{}, err
+	}
+	return GetUploadSession(ticketID, id)
+}
+
+// GetUploadSession returns an unexpired upload session of a ticket
+func GetUploadSession(ticketID int, id string) (models.UploadSession, error) {
+	var s models.UploadSession
+	err := config.DB.QueryRow(`
+		SELECT id, ticket_id, comment_id, kind, filename, size, received, checksum, uploader_id, uploader_nama, created_at, expires_at
+		FROM helpdesk_upload_sessions WHERE id = ? AND ticket_id = ? AND expires_at > ?
+	`, id, ticketID, time.Now()).Scan(&s.ID, &s.TicketID, &s.CommentID, &s.Kind, &s.Filename, &s.Size, &s.Offset,
+		&s.Checksum, &s.UploaderID, &s.UploaderNama, &s.CreatedAt, &s.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return s, ErrUploadNotFound
+	}
+	return s, err
+}
+
+// AppendUploadChunk stores a chunk of size bytes at offset, which must equal the bytes
+// received so far. A chunk is kept only once it has been received whole; after a broken
+// connection the client resends it from the session's offset.
+// The chunk that completes the file verifies the checksum and returns the new attachment.
+func AppendUploadChunk(session models.UploadSession, offset int64, chunk io.Reader, size int64) (models.UploadSession, *models.Attachment, error) {
+	if offset != session.Offset {
+		return session, nil, ErrUploadOffsetMismatch
+	}
+	if size > session.Size-offset {
+		return session, nil, ErrUploadChunkTooLarge
+	}
+
+	if size > 0 {
+		key, err := uploadChunkKey(session.ID, offset)
+		if err != nil {
+			return session, nil, err
+		}
+		if err := FileStorage.Put(key, io.LimitReader(chunk, size), size, "application/octet-stream"); err != nil {
+			FileStorage.Delete(key)
+			return session, nil, err
+		}
+
+		recorded, err := recordUploadChunk(session, offset, size, key)
+		if err != nil || !recorded {
+			FileStorage.Delete(key)
+		}
+		if err != nil {
+			return session, nil, err
+		}
+		if !recorded {
+			// Another request stored this offset first, or the session is gone
+			current, err := GetUploadSession(session.TicketID, session.ID)
+			if err != nil {
+				return current, nil, err
+			}
+			return current, nil, ErrUploadOffsetMismatch
+		}
+		session.Offset = offset + size
+		session.ExpiresAt = time.Now().Add(uploadSessionTTL())
+	}
+
+	if session.Offset < session.Size {
+		return session, nil, nil
+	}
+	att, err := completeUpload(session)
+	if err != nil {
+		return session, nil, err
+	}
+	return session, &att, nil
+}
+
+// recordUploadChunk adds a stored chunk to its session if the session is still at offset
+func recordUploadChunk(session models.UploadSession, offset, size int64, key string) (bool, error) {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	res, err := tx.Exec(`
+		UPDATE helpdesk_upload_sessions SET received = ?, expires_at = ?
+		WHERE id = ? AND received = ? AND expires_at > ?
+	`, offset+size, now.Add(uploadSessionTTL()), session.ID, offset, now)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO helpdesk_upload_chunks (session_id, start_offset, size, storage_key) VALUES (?, ?, ?, ?)
+	`, session.ID, offset, size, key); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// uploadCompletionLease is how long a session stays claimed by the request finalising it.
+// A request that dies meanwhile leaves the session to be retried once the lease runs out.
+const uploadCompletionLease = 15 * time.Minute
+
+// errUploadAttached is returned inside the recording transaction when another request
+// attached the session's file first
+var errUploadAttached = errors.New("upload already attached")
+
+// completeUpload verifies an assembled file and attaches it to the ticket. Sessions whose
+// file is wrong are discarded; on other errors the client may retry with an empty chunk.
+// The file is read without holding locks: the session is claimed in a short transaction,
+// and the attachment ID is recorded on it with the attachment, so a retry after a failed
+// cleanup returns that attachment instead of attaching the file twice.
+func completeUpload(session models.UploadSession) (models.Attachment, error) {
+	keys, attachmentID, err := claimUploadCompletion(session)
+	if err != nil {
+		return models.Attachment{}, err
+	}
+	if attachmentID != nil {
+		return completedUpload(session, *attachmentID, keys)
+	}
+	open := func() (io.ReadCloser, error) { return &chunkReader{keys: keys}, nil }
+
+	src, _ := open()
+	hash := sha256.New()
+	_, err = io.Copy(hash, src)
+	src.Close()
+	if err != nil {
+		releaseUploadCompletion(session.ID)
+		return models.Attachment{}, err
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != session.Checksum {
+		dropUploadSession(session.ID, keys)
+		return models.Attachment{}, ErrUploadChecksum
+	}
+
+	file := UploadedFile{
+		Name:    session.Filename,
+		Size:    session.Size,
+		MaxSize: maxResumableUploadSize(),
+		Open:    open,
+	}
+	uploader := Actor{UserID: session.UploaderID, Nama: session.UploaderNama}
+
+	att, err := saveAttachment(session.TicketID, session.CommentID, session.Kind, file, uploader, func(tx *sql.Tx, attachmentID int) error {
+		res, err := tx.Exec(`
+			UPDATE helpdesk_upload_sessions SET attachment_id = ? WHERE id = ? AND attachment_id IS NULL
+		`, attachmentID, session.ID)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			if err == nil {
+				err = errUploadAttached
+			}
+			return err
+		}
+		return nil
+	})
+	if errors.Is(err, errUploadAttached) {
+		// A retry that outlived our lease got there first
+		return completeUpload(session)
+	}
+	if err != nil {
+		if isUploadRejected(err) {
+			dropUploadSession(session.ID, keys)
+		} else {
+			releaseUploadCompletion(session.ID)
+		}
+		return att, err
+	}
+
+	if err := dropUploadSession(session.ID, keys); err != nil {
+		log.Println("Failed to remove completed upload session:", err)
+	}
+	return att, nil
+}
+
+// claimUploadCompletion marks a complete session as being finalised and returns its
+// chunks, or the ID of the attachment it already became
+func claimUploadCompletion(session models.UploadSession) ([]string, *int, error) {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	var received int64
+	var attachmentID *int
+	var completing bool
+	err = tx.QueryRow(`
+		SELECT received, attachment_id, COALESCE(completing_until > ?, 0)
+		FROM helpdesk_upload_sessions WHERE id = ? FOR UPDATE
+	`, now, session.ID).Scan(&received, &attachmentID, &completing)
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keys, err := uploadChunkKeys(tx, session.ID)
+	if err != nil || attachmentID != nil {
+		return keys, attachmentID, err
+	}
+	if received != session.Size {
+		return nil, nil, ErrUploadOffsetMismatch
+	}
+	if completing {
+		return nil, nil, ErrUploadInProgress
+	}
+
+	// The session must not expire while it is being finalised
+	until := now.Add(uploadCompletionLease)
+	if _, err := tx.Exec(`
+		UPDATE helpdesk_upload_sessions SET completing_until = ?, expires_at = GREATEST(expires_at, ?) WHERE id = ?
+	`, until, until, session.ID); err != nil {
+		return nil, nil, err
+	}
+	return keys, nil, tx.Commit()
+}
+
+// releaseUploadCompletion lets a failed completion be retried right away
+func releaseUploadCompletion(id string) {
+	if _, err := config.DB.Exec(`UPDATE helpdesk_upload_sessions SET completing_until = NULL WHERE id = ?`, id); err != nil {
+		log.Println("Failed to release upload session:", err)
+	}
+}
+
+// completedUpload returns the attachment a session already became and removes the session
+func completedUpload(session models.UploadSession, attachmentID int, keys []string) (models.Attachment, error) {
+	att, err := GetAttachment(session.TicketID, attachmentID, true)
+	if err != nil {
+		return att, err
+	}
+	if err := dropUploadSession(session.ID, keys); err != nil {
+		log.Println("Failed to remove completed upload session:", err)
+	}
+	if att.ScanStatus == ScanRejected {
+		return att, ErrFileInfected
+	}
+	return att, nil
+}
+
+// chunkReader reads the chunks of an upload one after the other, opening each when needed
+type chunkReader struct {
+	keys    []string
+	current io.ReadCloser
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.keys) == 0 {
+				return 0, io.EOF
+			}
+			rc, _, err := FileStorage.Open(r.keys[0])
+			if err != nil {
+				return 0, err
+			}
+			r.current, r.keys = rc, r.keys[1:]
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *chunkReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	return r.current.Close()
+}
+
+// uploadChunkKeys returns the storage keys of a session's chunks in file order
+func uploadChunkKeys(tx *sql.Tx, sessionID string) ([]string, error) {
+	rows, err := tx.Query(`
+		SELECT storage_key FROM helpdesk_upload_chunks WHERE session_id = ? ORDER BY start_offset
+	`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// removeUploadSession deletes a session locked by tx, commits, then deletes its chunks
+func removeUploadSession(tx *sql.Tx, id string, keys []string) error {
+	if _, err := tx.Exec(`DELETE FROM helpdesk_upload_chunks WHERE session_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM helpdesk_upload_sessions WHERE id = ?`, id); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := FileStorage.Delete(key); err != nil {
+			log.Println("Failed to delete upload chunk:", err)
+		}
+	}
+	return nil
+}
+
+// dropUploadSession removes a claimed session, which can no longer receive chunks
+func dropUploadSession(id string, keys []string) error {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	return removeUploadSession(tx, id, keys)
+}
+
+// isUploadRejected reports whether SaveAttachment refused the file itself
+func isUploadRejected(err error) bool {
+	for _, target := range []error{ErrFileEmpty, ErrFileTooLarge, ErrFileTypeNotAllowed, ErrFileExtensionMismatch, ErrImageInvalid, ErrFileInfected} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// CancelUploadSession discards an upload session and its data
+func CancelUploadSession(session models.UploadSession) error {
+	return discardUploadSession(session.ID)
+}
+
+func discardUploadSession(id string) error {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the session so no chunk is recorded after its chunks are listed
+	var completing bool
+	err = tx.QueryRow(`
+		SELECT COALESCE(completing_until > ?, 0) FROM helpdesk_upload_sessions WHERE id = ? FOR UPDATE
+	`, time.Now(), id).Scan(&completing)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Its chunks are being read into an attachment
+	if completing {
+		return ErrUploadInProgress
+	}
+	keys, err := uploadChunkKeys(tx, id)
+	if err != nil {
+		return err
+	}
+	return removeUploadSession(tx, id, keys)
+}
+
+// StartUploadCleanup periodically removes expired upload sessions and their data
+func StartUploadCleanup() {
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := cleanupUploadSessions(); err != nil {
+				log.Println("Upload cleanup failed:", err)
+			}
+		}
+	}()
+}
+
+func cleanupUploadSessions() error {
+	rows, err := config.DB.Query(`SELECT id FROM helpdesk_upload_sessions WHERE expires_at <= ?`, time.Now())
+	if err != nil {
+		return err
+	}
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		if err := discardUploadSession(id); err != nil && !errors.Is(err, ErrUploadInProgress) {
+			return err
+		}
+	}
+	return nil
+}
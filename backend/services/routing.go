@@ -0,0 +1,250 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+
+	"helpdesk-backend/config"
+	"helpdesk-backend/models"
+)
+
+var ErrUnknownStrategy = errors.New("Strategi routing tidak dikenal")
+
+// SystemActor is used for changes made automatically by the helpdesk
+var SystemActor = Actor{UserID: "system", Nama: "Sistem", Role: RoleAdmin}
+
+// RoutingStrategy picks a staff member for a new ticket among the available candidates
+type RoutingStrategy interface {
+	Pick(rule models.RoutingRule, ticket RoutingTicket, candidates []Staff) (Staff, bool, error)
+}
+
+// RoutingTicket is the ticket being routed
+type RoutingTicket struct {
+	ID         int
+	Categories []int // IDs of the ticket category and its ancestors, nearest first
+}
+
+var routingStrategies = map[string]RoutingStrategy{
+	"round_robin":  roundRobinStrategy{},
+	"least_loaded": leastLoadedStrategy{},
+	"skill":        skillStrategy{},
+}
+
+// RegisterRoutingStrategy makes a strategy available to routing rules under the given name
+func RegisterRoutingStrategy(name string, strategy RoutingStrategy) {
+	routingStrategies[name] = strategy
+}
+
+// IsValidStrategy reports whether a routing strategy is registered
+func IsValidStrategy(name string) bool {
+	_, ok := routingStrategies[name]
+	return ok
+}
+
+// AutoAssignTicket assigns a new ticket according to the routing rule of its category,
+// or of the nearest parent category with a rule. It returns nil when there is no
+// active rule or nobody is available.
+func AutoAssignTicket(ticketID int) (*Staff, error) {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var teamID, categoryID *int
+	var ticketNumber, subject string
+	err = tx.QueryRow(`
+		SELECT team_id, ticket_number, subject, category_id FROM helpdesk_tickets WHERE id = ?
+	`, ticketID).Scan(&teamID, &ticketNumber, &subject, &categoryID)
+	if err != nil {
+		return nil, err
+	}
+	lineage, err := ticketCategoryLineage(categoryID)
+	if err != nil {
+		return nil, err
+	}
+	ticket := RoutingTicket{ID: ticketID, Categories: lineage}
+
+	// Lock the rule so concurrent tickets advance the round-robin cursor in turn
+	in, order, args := nearestCategoryOrder("category_id", ticket.Categories)
+	var rule models.RoutingRule
+	err = tx.QueryRow(`
+		SELECT category_id, category, strategy, last_assignee_id, is_active, updated_at
+		FROM helpdesk_routing_rules WHERE `+in+` AND is_active = 1
+		ORDER BY `+order+` LIMIT 1 FOR UPDATE
+	`, append(args, args...)...).Scan(&rule.CategoryID, &rule.Category, &rule.Strategy, &rule.LastAssigneeID,
+		&rule.IsActive, &rule.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	strategy, ok := routingStrategies[rule.Strategy]
+	if !ok {
+		return nil, ErrUnknownStrategy
+	}
+
+	available, err := ListStaff(true)
+	if err != nil {
+		return nil, err
+	}
+	// Roles without ticket:work (e.g. unit_manager) are never routed tickets
+	candidates := []Staff{}
+	for _, s := range available {
+		if s.CanWorkTickets() {
+			candidates = append(candidates, s)
+		}
+	}
+
+	// Only members of the ticket's team are eligible when the team has members
+	if teamID != nil {
+		members, err := TeamMemberIDs(*teamID)
+		if err != nil {
+			return nil, err
+		}
+		if len(members) > 0 {
+			candidates = filterStaff(candidates, members)
+		}
+	}
+
+	staff, ok, err := strategy.Pick(rule, ticket, candidates)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	result, err := tx.Exec(`
+		UPDATE helpdesk_tickets SET assignee_id = ?, dikerjakan_oleh = ?
+		WHERE id = ? AND assignee_id IS NULL
+	`, staff.UserID, staff.Nama, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, nil
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE helpdesk_routing_rules SET last_assignee_id = ? WHERE category_id = ?
+	`, staff.UserID, rule.CategoryID); err != nil {
+		return nil, err
+	}
+
+	if err := RecordTicketEventNote(tx, ticketID, EventAssigned, SystemActor, "", staff.Nama, "Otomatis: "+rule.Strategy); err != nil {
+		return nil, err
+	}
+	if err := NotifyTicketAssigned(tx, ticketID, ticketNumber, subject, staff.Nama, SystemActor.Nama, ""); err != nil {
+		return nil, err
+	}
+	if err := NotifyAssignee(tx, ticketID, ticketNumber, subject, staff.UserID, SystemActor.Nama, "Otomatis: "+rule.Strategy); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &staff, nil
+}
+
+// roundRobinStrategy cycles through candidates ordered by user ID
+type roundRobinStrategy struct{}
+
+func (roundRobinStrategy) Pick(rule models.RoutingRule, _ RoutingTicket, candidates []Staff) (Staff, bool, error) {
+	if len(candidates) == 0 {
+		return Staff{}, false, nil
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].UserID < candidates[j].UserID })
+
+	if rule.LastAssigneeID != nil {
+		for _, s := range candidates {
+			if s.UserID > *rule.LastAssigneeID {
+				return s, true, nil
+			}
+		}
+	}
+	return candidates[0], true, nil
+}
+
+// leastLoadedStrategy picks the candidate with the fewest open tickets
+type leastLoadedStrategy struct{}
+
+func (leastLoadedStrategy) Pick(rule models.RoutingRule, _ RoutingTicket, candidates []Staff) (Staff, bool, error) {
+	if len(candidates) == 0 {
+		return Staff{}, false, nil
+	}
+
+	load, err := openTicketCounts()
+	if err != nil {
+		return Staff{}, false, err
+	}
+
+	best := candidates[0]
+	for _, s := range candidates[1:] {
+		if load[s.UserID] < load[best.UserID] {
+			best = s
+		}
+	}
+	return best, true, nil
+}
+
+// skillStrategy picks the least loaded candidate skilled in the ticket category or one
+// of its parent categories
+type skillStrategy struct{}
+
+func (skillStrategy) Pick(rule models.RoutingRule, ticket RoutingTicket, candidates []Staff) (Staff, bool, error) {
+	categories := map[int]bool{}
+	for _, id := range ticket.Categories {
+		categories[id] = true
+	}
+
+	skilled := []Staff{}
+	for _, s := range candidates {
+		for _, skill := range s.Skills {
+			if categories[skill.CategoryID] {
+				skilled = append(skilled, s)
+				break
+			}
+		}
+	}
+	return leastLoadedStrategy{}.Pick(rule, ticket, skilled)
+}
+
+func filterStaff(staff []Staff, userIDs []string) []Staff {
+	allowed := map[string]bool{}
+	for _, id := range userIDs {
+		allowed[id] = true
+	}
+
+	filtered := []Staff{}
+	for _, s := range staff {
+		if allowed[s.UserID] {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered
+}
+
+func openTicketCounts() (map[string]int, error) {
+	rows, err := config.DB.Query(`
+		SELECT assignee_id, COUNT(*) FROM helpdesk_tickets
+		WHERE assignee_id IS NOT NULL AND status IN ('baru', 'dikerjakan')
+		GROUP BY assignee_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var userID string
+		var count int
+		if err := rows.Scan(&userID, &count); err != nil {
+			return nil, err
+		}
+		counts[userID] = count
+	}
+	return counts, nil
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Scan status of an attachment
+const (
+	ScanUnscanned = "unscanned"
+	ScanClean     = "clean"
+	ScanRejected  = "rejected"
+)
+
+var (
+	ErrFileInfected      = errors.New("File terdeteksi mengandung malware")
+	ErrScanUnavailable   = errors.New("Pemindai file tidak tersedia, coba lagi nanti")
+	errClamdUnknownReply = errors.New("unexpected clamd reply")
+)
+
+// ScanResult is the verdict of a malware scanner
+type ScanResult struct {
+	Infected  bool
+	Signature string // name of the detected malware
+}
+
+// Scanner inspects uploaded files for malware
+type Scanner interface {
+	Scan(r io.Reader) (ScanResult, error)
+}
+
+// UploadScanner scans every upload; nil disables scanning. Set up by InitScanner.
+var UploadScanner Scanner
+
+// InitScanner selects the upload scanner from UPLOAD_SCANNER ("", "clamd" or "command")
+func InitScanner() {
+	timeout, err := time.ParseDuration(os.Getenv("UPLOAD_SCAN_TIMEOUT"))
+	if err != nil || timeout <= 0 {
+		timeout = time.Minute
+	}
+
+	switch scanner := os.Getenv("UPLOAD_SCANNER"); scanner {
+	case "", "none":
+		UploadScanner = nil
+	case "clamd":
+		address := os.Getenv("CLAMD_ADDRESS")
+		if address == "" {
+			address = "tcp://127.0.0.1:3310"
+		}
+		UploadScanner = NewClamdScanner(address, timeout)
+	case "command":
+		command := strings.Fields(os.Getenv("UPLOAD_SCAN_COMMAND"))
+		if len(command) == 0 {
+			log.Fatal("UPLOAD_SCAN_COMMAND is required when UPLOAD_SCANNER=command")
+		}
+		UploadScanner = NewCommandScanner(command, timeout)
+	default:
+		log.Fatalf("Unknown UPLOAD_SCANNER %q", scanner)
+	}
+}
+
+// scanUpload runs the configured scanner over an upload. When the scanner fails the upload
+// is refused, unless UPLOAD_SCAN_FAIL_OPEN=true lets it through unscanned.
+func scanUpload(file UploadedFile) (string, ScanResult, error) {
+	if UploadScanner == nil {
+		return ScanUnscanned, ScanResult{}, nil
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return "", ScanResult{}, err
+	}
+	defer src.Close()
+
+	result, err := UploadScanner.Scan(src)
+	if err != nil {
+		log.Println("Upload scan failed:", err)
+		if os.Getenv("UPLOAD_SCAN_FAIL_OPEN") == "true" {
+			return ScanUnscanned, ScanResult{}, nil
+		}
+		return "", ScanResult{}, ErrScanUnavailable
+	}
+	if result.Infected {
+		return ScanRejected, result, nil
+	}
+	return ScanClean, result, nil
+}
+
+// clamdScanner streams files to a ClamAV daemon with the INSTREAM command
+type clamdScanner struct {
+	network, address string
+	timeout          time.Duration
+}
+
+// NewClamdScanner returns a Scanner for a clamd socket, "unix:///path/clamd.sock" or "tcp://host:3310"
+func NewClamdScanner(address string, timeout time.Duration) Scanner {
+	if path, ok := strings.CutPrefix(address, "unix://"); ok {
+		return &clamdScanner{network: "unix", address: path, timeout: timeout}
+	}
+	return &clamdScanner{network: "tcp", address: strings.TrimPrefix(address, "tcp://"), timeout: timeout}
+}
+
+func (s *clamdScanner) Scan(r io.Reader) (ScanResult, error) {
+	conn, err := net.DialTimeout(s.network, s.address, s.timeout)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(s.timeout))
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return ScanResult{}, err
+	}
+
+	// Chunks are prefixed with their length; a zero length ends the stream
+	buf := make([]byte, 64<<10)
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			var size [4]byte
+			binary.BigEndian.PutUint32(size[:], uint32(n))
+			if _, err := conn.Write(append(size[:], buf[:n]...)); err != nil {
+				return ScanResult{}, err
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return ScanResult{}, readErr
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return ScanResult{}, err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return ScanResult{}, err
+	}
+	return parseClamdReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// parseClamdReply reads "stream: OK", "stream: <signature> FOUND" or "... ERROR"
+func parseClamdReply(reply string) (ScanResult, error) {
+	_, verdict, _ := strings.Cut(reply, ": ")
+	switch {
+	case verdict == "OK":
+		return ScanResult{}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return ScanResult{Infected: true, Signature: strings.TrimSuffix(verdict, " FOUND")}, nil
+	case strings.HasSuffix(verdict, " ERROR"):
+		return ScanResult{}, fmt.Errorf("clamd: %s", verdict)
+	}
+	return ScanResult{}, fmt.Errorf("%w: %q", errClamdUnknownReply, reply)
+}
+
+// commandScanner pipes files into an external command (e.g. "clamdscan --no-summary -").
+// Exit status 0 means clean and 1 infected, as with the ClamAV tools; the output of an
+// infected scan is kept as the signature.
+type commandScanner struct {
+	command []string
+	timeout time.Duration
+}
+
+// NewCommandScanner returns a Scanner that runs a command with the file on stdin
+func NewCommandScanner(command []string, timeout time.Duration) Scanner {
+	return &commandScanner{command: command, timeout: timeout}
+}
+
+func (s *commandScanner) Scan(r io.Reader) (ScanResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.command[0], s.command[1:]...)
+	cmd.Stdin = r
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return ScanResult{}, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		signature := strings.TrimSpace(output.String())
+		if i := strings.LastIndexByte(signature, '\n'); i >= 0 {
+			signature = signature[i+1:]
+		}
+		if _, found, ok := strings.Cut(signature, ": "); ok {
+			signature = strings.TrimSuffix(found, " FOUND")
+		}
+		return ScanResult{Infected: true, Signature: signature}, nil
+	}
+	return ScanResult{}, fmt.Errorf("%s: %w: %s", s.command[0], err, strings.TrimSpace(output.String()))
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClamd accepts one INSTREAM scan and answers it with reply(data). The data and
+// chunk sizes it received are sent on the returned channel.
+func fakeClamd(t *testing.T, reply func(data []byte) string) (string, <-chan clamdStream) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	streams := make(chan clamdStream, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		var stream clamdStream
+		r := bufio.NewReader(conn)
+		stream.command, _ = r.ReadString(0)
+		for {
+			var size [4]byte
+			if _, err := io.ReadFull(r, size[:]); err != nil {
+				return
+			}
+			n := binary.BigEndian.Uint32(size[:])
+			if n == 0 {
+				break
+			}
+			chunk := make([]byte, n)
+			if _, err := io.ReadFull(r, chunk); err != nil {
+				return
+			}
+			stream.chunks = append(stream.chunks, int(n))
+			stream.data = append(stream.data, chunk...)
+		}
+		streams <- stream
+		conn.Write([]byte(reply(stream.data) + "\x00"))
+	}()
+	return "tcp://" + ln.Addr().String(), streams
+}
+
+type clamdStream struct {
+	command string
+	chunks  []int
+	data    []byte
+}
+
+func TestClamdScanner(t *testing.T) {
+	tests := []struct {
+		name     string
+		reply    string
+		infected bool
+		sig      string
+		wantErr  bool
+	}{
+		{"clean", "stream: OK", false, "", false},
+		{"found", "stream: Win.Test.EICAR_HDB-1 FOUND", true, "Win.Test.EICAR_HDB-1", false},
+		{"error reply", "stream: Can't allocate memory ERROR", false, "", true},
+		{"unknown reply", "PONG", false, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address, streams := fakeClamd(t, func([]byte) string { return tt.reply })
+			// Larger than one 64 KiB frame so the file is split
+			file := bytes.Repeat([]byte("helpdesk"), 10<<10)
+
+			result, err := NewClamdScanner(address, 5*time.Second).Scan(bytes.NewReader(file))
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Scan error = %v, want error %v", err, tt.wantErr)
+			}
+			if result.Infected != tt.infected || result.Signature != tt.sig {
+				t.Errorf("Scan = %+v, want infected %v signature %q", result, tt.infected, tt.sig)
+			}
+
+			stream := <-streams
+			if stream.command != "zINSTREAM\x00" {
+				t.Errorf("command %q", stream.command)
+			}
+			if !bytes.Equal(stream.data, file) {
+				t.Errorf("clamd received %d bytes, want %d", len(stream.data), len(file))
+			}
+			if len(stream.chunks) != 2 || stream.chunks[0] != 64<<10 {
+				t.Errorf("chunk sizes %v", stream.chunks)
+			}
+		})
+	}
+}
+
+func TestClamdScannerUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := ln.Addr().String()
+	ln.Close()
+
+	if _, err := NewClamdScanner("tcp://"+address, time.Second).Scan(strings.NewReader("x")); err == nil {
+		t.Error("Scan succeeded without a clamd listening")
+	}
+}
+
+func TestParseClamdReply(t *testing.T) {
+	tests := []struct {
+		reply    string
+		infected bool
+		sig      string
+		wantErr  error
+	}{
+		{"stream: OK", false, "", nil},
+		{"stream: Eicar-Signature FOUND", true, "Eicar-Signature", nil},
+		{"stream: Can't allocate memory ERROR", false, "", nil},
+		{"", false, "", errClamdUnknownReply},
+		{"stream: maybe", false, "", errClamdUnknownReply},
+	}
+
+	for _, tt := range tests {
+		result, err := parseClamdReply(tt.reply)
+		if result.Infected != tt.infected || result.Signature != tt.sig {
+			t.Errorf("parseClamdReply(%q) = %+v", tt.reply, result)
+		}
+		if strings.HasSuffix(tt.reply, " ERROR") {
+			if err == nil || !strings.Contains(err.Error(), "Can't allocate memory") {
+				t.Errorf("parseClamdReply(%q) error = %v", tt.reply, err)
+			}
+		} else if !errors.Is(err, tt.wantErr) {
+			t.Errorf("parseClamdReply(%q) error = %v, want %v", tt.reply, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCommandScanner(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	tests := []struct {
+		name     string
+		script   string
+		timeout  time.Duration
+		infected bool
+		sig      string
+		wantErr  bool
+	}{
+		{"exit 0 is clean", "cat >/dev/null", time.Minute, false, "", false},
+		{"exit 1 is infected", `cat >/dev/null; echo "scanning"; echo "stdin: Eicar-Signature FOUND"; exit 1`, time.Minute, true, "Eicar-Signature", false},
+		{"file is on stdin", `grep -q X5O && { echo "stdin: Found-On-Stdin FOUND"; exit 1; }; exit 0`, time.Minute, true, "Found-On-Stdin", false},
+		{"exit 2 is an error", `cat >/dev/null; echo "database missing" >&2; exit 2`, time.Minute, false, "", true},
+		{"timeout is an error", "exec sleep 5", 100 * time.Millisecond, false, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := NewCommandScanner([]string{"sh", "-c", tt.script}, tt.timeout)
+			result, err := scanner.Scan(strings.NewReader("X5O!P%@AP"))
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Scan error = %v, want error %v", err, tt.wantErr)
+			}
+			if result.Infected != tt.infected || result.Signature != tt.sig {
+				t.Errorf("Scan = %+v, want infected %v signature %q", result, tt.infected, tt.sig)
+			}
+		})
+	}
+}
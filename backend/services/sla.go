@@ -0,0 +1,208 @@
+package services
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"helpdesk-backend/config"
+	"helpdesk-backend/models"
+)
+
+// slaWarningWindow returns how long before a deadline a ticket is considered at risk
+func slaWarningWindow() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("SLA_WARNING_MINUTES"))
+	if err != nil || minutes <= 0 {
+		minutes = 30
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// FindSLAPolicy returns the most specific active policy for a category lineage (see
+// CategoryLineage) and priority. The nearest category wins; policies without a
+// category or priority act as wildcards.
+func FindSLAPolicy(lineage []int, priority string) (*models.SLAPolicy, error) {
+	in, order, ids := nearestCategoryOrder("category_id", lineage)
+	args := append(append(append([]interface{}{}, ids...), priority), ids...)
+
+	var p models.SLAPolicy
+	err := config.DB.QueryRow(`
+		SELECT id, category_id, category, priority, response_minutes, resolution_minutes, is_active, created_at, updated_at
+		FROM helpdesk_sla_policies
+		WHERE is_active = 1
+		  AND (`+in+` OR category_id IS NULL)
+		  AND (priority = ? OR priority IS NULL)
+		ORDER BY (category_id IS NOT NULL) DESC, `+order+`, (priority IS NOT NULL) DESC, id ASC
+		LIMIT 1
+	`, args...).Scan(&p.ID, &p.CategoryID, &p.Category, &p.Priority, &p.ResponseMinutes,
+		&p.ResolutionMinutes, &p.IsActive, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ApplySLAPolicy computes and stores the response and resolution due times of a ticket,
+// as part of the transaction that created it or changed its priority
+func ApplySLAPolicy(tx *sql.Tx, ticketID int) error {
+	var priority string
+	var categoryID *int
+	var createdAt time.Time
+	err := tx.QueryRow(`
+		SELECT category_id, priority, created_at FROM helpdesk_tickets WHERE id = ?
+	`, ticketID).Scan(&categoryID, &priority, &createdAt)
+	if err != nil {
+		return err
+	}
+	lineage, err := ticketCategoryLineage(categoryID)
+	if err != nil {
+		return err
+	}
+
+	var responseDue, resolutionDue *time.Time
+	p, err := FindSLAPolicy(lineage, priority)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if p != nil {
+		r := createdAt.Add(time.Duration(p.ResponseMinutes) * time.Minute)
+		d := createdAt.Add(time.Duration(p.ResolutionMinutes) * time.Minute)
+		responseDue, resolutionDue = &r, &d
+	}
+
+	_, err = tx.Exec(`
+		UPDATE helpdesk_tickets
+		SET response_due_at = ?, resolution_due_at = ?, sla_response_warned = 0, sla_resolution_warned = 0
+		WHERE id = ?
+	`, responseDue, resolutionDue, ticketID)
+	return err
+}
+
+// MarkFirstResponse records the first staff response on a ticket if none exists yet
+func MarkFirstResponse(ticketID int) error {
+	_, err := config.DB.Exec(`
+		UPDATE helpdesk_tickets SET first_response_at = NOW()
+		WHERE id = ? AND first_response_at IS NULL
+	`, ticketID)
+	return err
+}
+
+// ComputeSLAFlags sets the breach and at-risk flags of a ticket
+func ComputeSLAFlags(t *models.Ticket, now time.Time) {
+	// A ticket cancelled before it was resolved has no SLA to meet
+	if t.Status == StatusDitutup && t.ResolvedAt == nil {
+		return
+	}
+
+	window := slaWarningWindow()
+
+	if t.ResponseDueAt != nil {
+		respondedAt := now
+		if t.FirstResponseAt != nil {
+			respondedAt = *t.FirstResponseAt
+		}
+		t.SLAResponseBreached = respondedAt.After(*t.ResponseDueAt)
+		if t.FirstResponseAt == nil && !t.SLAResponseBreached && t.ResponseDueAt.Sub(now) <= window {
+			t.SLAAtRisk = true
+		}
+	}
+
+	if t.ResolutionDueAt != nil {
+		resolvedAt := now
+		if t.ResolvedAt != nil {
+			resolvedAt = *t.ResolvedAt
+		}
+		t.SLAResolutionBreached = resolvedAt.After(*t.ResolutionDueAt)
+		if t.ResolvedAt == nil && !t.SLAResolutionBreached && t.ResolutionDueAt.Sub(now) <= window {
+			t.SLAAtRisk = true
+		}
+	}
+}
+
+// StartSLAChecker periodically alerts about open tickets that are about to breach their SLA
+func StartSLAChecker() {
+	interval, err := time.ParseDuration(os.Getenv("SLA_CHECK_INTERVAL"))
+	if err != nil || interval <= 0 {
+		interval = time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := checkSLA(); err != nil {
+				log.Println("SLA check failed:", err)
+			}
+		}
+	}()
+}
+
+// checkSLA warns about open tickets only; cancelled (ditutup) tickets never breach
+func checkSLA() error {
+	deadline := time.Now().Add(slaWarningWindow())
+
+	// Response deadlines
+	if err := warnSLA(`
+		SELECT id, ticket_number, subject, response_due_at FROM helpdesk_tickets
+		WHERE status = 'baru' AND first_response_at IS NULL
+		  AND sla_response_warned = 0 AND response_due_at IS NOT NULL AND response_due_at <= ?
+	`, "respon", "sla_response_warned", deadline); err != nil {
+		return err
+	}
+
+	// Resolution deadlines
+	return warnSLA(`
+		SELECT id, ticket_number, subject, resolution_due_at FROM helpdesk_tickets
+		WHERE status IN ('baru', 'dikerjakan')
+		  AND sla_resolution_warned = 0 AND resolution_due_at IS NOT NULL AND resolution_due_at <= ?
+	`, "penyelesaian", "sla_resolution_warned", deadline)
+}
+
+func warnSLA(query, kind, flagColumn string, deadline time.Time) error {
+	rows, err := config.DB.Query(query, deadline)
+	if err != nil {
+		return err
+	}
+
+	type dueTicket struct {
+		id           int
+		ticketNumber string
+		subject      string
+		dueAt        time.Time
+	}
+	due := []dueTicket{}
+	for rows.Next() {
+		var t dueTicket
+		if err := rows.Scan(&t.id, &t.ticketNumber, &t.subject, &t.dueAt); err != nil {
+			continue
+		}
+		due = append(due, t)
+	}
+	rows.Close()
+
+	for _, t := range due {
+		if err := warnTicketSLA(t.id, t.ticketNumber, t.subject, kind, flagColumn, t.dueAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// warnTicketSLA flags a ticket as warned and queues the warning in one transaction
+func warnTicketSLA(ticketID int, ticketNumber, subject, kind, flagColumn string, dueAt time.Time) error {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE helpdesk_tickets SET "+flagColumn+" = 1 WHERE id = ?", ticketID); err != nil {
+		return err
+	}
+	if err := NotifySLAWarning(tx, ticketID, ticketNumber, subject, kind, dueAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
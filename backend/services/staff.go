@@ -0,0 +1,143 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+
+	"helpdesk-backend/config"
+)
+
+var (
+	ErrStaffNotFound   = errors.New("Staf tidak ditemukan")
+	ErrStaffCannotWork = errors.New("Role staf tersebut tidak dapat mengerjakan tiket")
+)
+
+// Staff is a helpdesk admin/technician
+type Staff struct {
+	UserID      string       `json:"user_id"`
+	Nama        string       `json:"nama"`
+	Role        string       `json:"role"`
+	IsAvailable bool         `json:"is_available"`
+	Skills      []StaffSkill `json:"skills"`
+}
+
+// StaffSkill is a category a staff member handles, including its subcategories
+type StaffSkill struct {
+	CategoryID int    `json:"category_id"`
+	Category   string `json:"category"`
+}
+
+// FindStaff looks up a staff member in helpdesk_admins, with the name taken from SIK pegawai
+func FindStaff(userID string) (Staff, error) {
+	var s Staff
+	err := config.DB.QueryRow(`
+		SELECT a.user_id, COALESCE(p.nama, a.user_id), a.role, a.is_available
+		FROM helpdesk_admins a
+		LEFT JOIN pegawai p ON p.nik = a.user_id
+		WHERE a.user_id = ?
+	`, userID).Scan(&s.UserID, &s.Nama, &s.Role, &s.IsAvailable)
+	if err == sql.ErrNoRows {
+		return s, ErrStaffNotFound
+	}
+	return s, err
+}
+
+// CanWorkTickets reports whether a staff member's role allows working on tickets,
+// i.e. whether tickets may be assigned to them
+func (s Staff) CanWorkTickets() bool {
+	return RoleHasPermission(s.Role, PermTicketWork)
+}
+
+// ListStaff returns all staff members with their skills, optionally only available ones
+func ListStaff(onlyAvailable bool) ([]Staff, error) {
+	query := `
+		SELECT a.user_id, COALESCE(p.nama, a.user_id), a.role, a.is_available
+		FROM helpdesk_admins a
+		LEFT JOIN pegawai p ON p.nik = a.user_id`
+	if onlyAvailable {
+		query += " WHERE a.is_available = 1"
+	}
+	query += " ORDER BY a.user_id"
+
+	rows, err := config.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+
+	staff := []Staff{}
+	index := map[string]int{}
+	for rows.Next() {
+		s := Staff{Skills: []StaffSkill{}}
+		if err := rows.Scan(&s.UserID, &s.Nama, &s.Role, &s.IsAvailable); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[s.UserID] = len(staff)
+		staff = append(staff, s)
+	}
+	rows.Close()
+
+	skills, err := config.DB.Query(`
+		SELECT user_id, category_id, category FROM helpdesk_staff_skills ORDER BY category, category_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer skills.Close()
+
+	for skills.Next() {
+		var userID string
+		var skill StaffSkill
+		if err := skills.Scan(&userID, &skill.CategoryID, &skill.Category); err != nil {
+			return nil, err
+		}
+		if i, ok := index[userID]; ok {
+			staff[i].Skills = append(staff[i].Skills, skill)
+		}
+	}
+
+	return staff, nil
+}
+
+// SetStaffAvailability marks a staff member as available or unavailable for routing
+func SetStaffAvailability(userID string, available bool) error {
+	result, err := config.DB.Exec(`UPDATE helpdesk_admins SET is_available = ? WHERE user_id = ?`, available, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		if _, err := FindStaff(userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetStaffSkills replaces the categories a staff member is skilled in
+func SetStaffSkills(userID string, categoryIDs []int) error {
+	if _, err := FindStaff(userID); err != nil {
+		return err
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM helpdesk_staff_skills WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	for _, categoryID := range categoryIDs {
+		name, err := CategoryName(categoryID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+			INSERT IGNORE INTO helpdesk_staff_skills (user_id, category_id, category) VALUES (?, ?, ?)
+		`, userID, categoryID, name); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrStoredFileNotFound = errors.New("File not found in storage")
+
+// Storage keeps attachment files. Keys are relative slash-separated paths
+// such as "tickets/12/3f2a….png".
+type Storage interface {
+	Put(key string, r io.Reader, size int64, contentType string) error
+	// Open returns the file contents and size; ErrStoredFileNotFound if missing
+	Open(key string) (io.ReadCloser, int64, error)
+	Delete(key string) error
+}
+
+// FileStorage is the storage used for attachments, set up by InitStorage
+var FileStorage Storage = NewLocalStorage(UploadDir)
+
+// InitStorage selects the attachment storage from STORAGE_BACKEND ("local" or "s3")
+func InitStorage() {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "local":
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = UploadDir
+		}
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			log.Fatal("Failed to create upload directory:", err)
+		}
+		FileStorage = NewLocalStorage(dir)
+	case "s3":
+		s, err := NewS3Storage(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		})
+		if err != nil {
+			log.Fatal("Invalid S3 storage configuration:", err)
+		}
+		FileStorage = s
+	default:
+		log.Fatalf("Unknown STORAGE_BACKEND %q", backend)
+	}
+}
+
+// localStorage stores files below a directory on the local disk
+type localStorage struct {
+	root string
+}
+
+// NewLocalStorage returns a Storage backed by a local directory
+func NewLocalStorage(root string) Storage {
+	return &localStorage{root: root}
+}
+
+// path resolves a key inside the root directory, rejecting keys that escape it
+func (s *localStorage) path(key string) (string, error) {
+	root, err := filepath.Abs(s.root)
+	if err != nil {
+		return "", err
+	}
+	full := filepath.Join(root, filepath.Clean("/"+key))
+	if !strings.HasPrefix(full, root+string(filepath.Separator)) {
+		return "", ErrStoredFileNotFound
+	}
+	return full, nil
+}
+
+func (s *localStorage) Put(key string, r io.Reader, size int64, contentType string) error {
+	full, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(full), os.ModePerm); err != nil {
+		return err
+	}
+	out, err := os.Create(full)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		os.Remove(full)
+		return err
+	}
+	return out.Close()
+}
+
+func (s *localStorage) Open(key string) (io.ReadCloser, int64, error) {
+	full, err := s.path(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	f, err := os.Open(full)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, ErrStoredFileNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		f.Close()
+		return nil, 0, ErrStoredFileNotFound
+	}
+	return f, info.Size(), nil
+}
+
+func (s *localStorage) Delete(key string) error {
+	full, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(full); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// S3Config configures an S3-compatible object store (AWS S3, MinIO, ...)
+type S3Config struct {
+	Endpoint  string // e.g. https://s3.ap-southeast-1.amazonaws.com or http://localhost:9000
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+}
+
+// s3Storage talks to an S3-compatible API using path-style URLs and Signature V4
+type s3Storage struct {
+	cfg    S3Config
+	client *http.Client
+}
+
+// NewS3Storage returns a Storage backed by an S3-compatible bucket
+func NewS3Storage(cfg S3Config) (Storage, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY and S3_SECRET_KEY are required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	return &s3Storage{cfg: cfg, client: &http.Client{Timeout: 60 * time.Second}}, nil
+}
+
+func (s *s3Storage) Put(key string, r io.Reader, size int64, contentType string) error {
+	resp, err := s.do(http.MethodPut, key, r, size, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return s3Error(resp, key)
+}
+
+func (s *s3Storage) Open(key string) (io.ReadCloser, int64, error) {
+	resp, err := s.do(http.MethodGet, key, nil, 0, "")
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := s3Error(resp, key); err != nil {
+		resp.Body.Close()
+		return nil, 0, err
+	}
+	return resp.Body, resp.ContentLength, nil
+}
+
+func (s *s3Storage) Delete(key string) error {
+	resp, err := s.do(http.MethodDelete, key, nil, 0, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return s3Error(resp, key)
+}
+
+// do sends a signed request for an object. The payload is not hashed
+// (UNSIGNED-PAYLOAD) so uploads can be streamed.
+func (s *s3Storage) do(method, key string, body io.Reader, size int64, contentType string) (*http.Response, error) {
+	objectPath := "/" + s.cfg.Bucket + "/" + s3URIEncode(key)
+	req, err := http.NewRequest(method, s.cfg.Endpoint+objectPath, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	amzDate := time.Now().UTC().Format("20060102T150405Z")
+	payloadHash := "UNSIGNED-PAYLOAD"
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	canonicalRequest, signedHeaders := s3CanonicalRequest(method, objectPath, map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}, payloadHash)
+	scope, signature := s3Signature(s.cfg.SecretKey, s.cfg.Region, amzDate, canonicalRequest)
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, scope, signedHeaders, signature))
+
+	return s.client.Do(req)
+}
+
+// s3CanonicalRequest builds the Signature V4 canonical request of a request without a
+// query string; path must already be URI-encoded. Every header given (lowercase names)
+// is signed.
+func s3CanonicalRequest(method, path string, headers map[string]string, payloadHash string) (canonical, signedHeaders string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders = strings.Join(names, ";")
+
+	canonical = strings.Join([]string{method, path, "", canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+	return canonical, signedHeaders
+}
+
+// s3Signature signs a canonical request, returning the credential scope and the signature
+func s3Signature(secretKey, region, amzDate, canonicalRequest string) (scope, signature string) {
+	day := amzDate[:8]
+	scope = day + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex(canonicalRequest)
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return scope, hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func s3Error(resp *http.Response, key string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrStoredFileNotFound
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("s3 %s: %s: %s", key, resp.Status, strings.TrimSpace(string(msg)))
+}
+
+// s3URIEncode encodes an object key as required by Signature V4, keeping slashes
+func s3URIEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// The GET Object example of the AWS Signature V4 documentation
+func TestS3SignatureVector(t *testing.T) {
+	emptyHash := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	canonical, signedHeaders := s3CanonicalRequest("GET", "/test.txt", map[string]string{
+		"host":                 "examplebucket.s3.amazonaws.com",
+		"range":                "bytes=0-9",
+		"x-amz-content-sha256": emptyHash,
+		"x-amz-date":           "20130524T000000Z",
+	}, emptyHash)
+
+	wantCanonical := "GET\n/test.txt\n\n" +
+		"host:examplebucket.s3.amazonaws.com\n" +
+		"range:bytes=0-9\n" +
+		"x-amz-content-sha256:" + emptyHash + "\n" +
+		"x-amz-date:20130524T000000Z\n\n" +
+		"host;range;x-amz-content-sha256;x-amz-date\n" +
+		emptyHash
+	if canonical != wantCanonical {
+		t.Errorf("canonical request:\n%s\nwant:\n%s", canonical, wantCanonical)
+	}
+	if signedHeaders != "host;range;x-amz-content-sha256;x-amz-date" {
+		t.Errorf("signed headers %q", signedHeaders)
+	}
+	if got := sha256Hex(canonical); got != "7344ae5b7ee6c3e7e6b0fe0640412a37625d1fbfff95c48bbb2dc43964946972" {
+		t.Errorf("canonical request hash %s", got)
+	}
+
+	scope, signature := s3Signature("wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY", "us-east-1", "20130524T000000Z", canonical)
+	if scope != "20130524/us-east-1/s3/aws4_request" {
+		t.Errorf("scope %q", scope)
+	}
+	if signature != "f0e8bdb87c964420e857bd35b5d6ed310bd44f0170aba48dd91039c6036bdb41" {
+		t.Errorf("signature %s", signature)
+	}
+}
+
+// fakeS3 is a MinIO-style stand-in: path-style objects in memory, with requests
+// rejected unless their Signature V4 checks out
+type fakeS3 struct {
+	accessKey, secretKey, region string
+
+	mu      sync.Mutex
+	objects map[string][]byte // escaped path → contents
+	types   map[string]string
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{
+		accessKey: "AKIDEXAMPLE", secretKey: "secret", region: "ap-southeast-1",
+		objects: map[string][]byte{}, types: map[string]string{},
+	}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !f.validSignature(r) {
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>", http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := r.URL.EscapedPath()
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[path] = body
+		f.types[path] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		body, ok := f.objects[path]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		w.Write(body)
+	case http.MethodDelete:
+		delete(f.objects, path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// validSignature recomputes the signature from the request as received
+func (f *fakeS3) validSignature(r *http.Request) bool {
+	auth := strings.TrimPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+	fields := map[string]string{}
+	for _, part := range strings.Split(auth, ", ") {
+		name, value, _ := strings.Cut(part, "=")
+		fields[name] = value
+	}
+	amzDate := r.Header.Get("X-Amz-Date")
+	if len(amzDate) < 8 || fields["Credential"] != f.accessKey+"/"+amzDate[:8]+"/"+f.region+"/s3/aws4_request" {
+		return false
+	}
+
+	names := strings.Split(fields["SignedHeaders"], ";")
+	if !sort.StringsAreSorted(names) {
+		return false
+	}
+	var canonical strings.Builder
+	canonical.WriteString(r.Method + "\n" + r.URL.EscapedPath() + "\n" + r.URL.RawQuery + "\n")
+	for _, name := range names {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonical.WriteString(name + ":" + value + "\n")
+	}
+	canonical.WriteString("\n" + fields["SignedHeaders"] + "\n" + r.Header.Get("X-Amz-Content-Sha256"))
+
+	sign := func(key []byte, data string) []byte {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(data))
+		return mac.Sum(nil)
+	}
+	hash := sha256.Sum256([]byte(canonical.String()))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + amzDate[:8] + "/" + f.region + "/s3/aws4_request\n" + hex.EncodeToString(hash[:])
+	key := sign([]byte("AWS4"+f.secretKey), amzDate[:8])
+	key = sign(key, f.region)
+	key = sign(key, "s3")
+	key = sign(key, "aws4_request")
+	return hmac.Equal([]byte(hex.EncodeToString(sign(key, stringToSign))), []byte(fields["Signature"]))
+}
+
+func TestS3StorageRoundTrip(t *testing.T) {
+	fake, srv := newFakeS3(t)
+	storage, err := NewS3Storage(S3Config{
+		Endpoint: srv.URL + "/", Region: fake.region, Bucket: "helpdesk",
+		AccessKey: fake.accessKey, SecretKey: fake.secretKey,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := "tickets/12/bukti masalah+1.png"
+	if err := storage.Put(key, strings.NewReader("png data"), 8, "image/png"); err != nil {
+		t.Fatal("Put:", err)
+	}
+	stored := "/helpdesk/tickets/12/bukti%20masalah%2B1.png"
+	if string(fake.objects[stored]) != "png data" || fake.types[stored] != "image/png" {
+		t.Fatalf("stored objects %v, types %v", fake.objects, fake.types)
+	}
+
+	rc, size, err := storage.Open(key)
+	if err != nil {
+		t.Fatal("Open:", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "png data" || size != 8 {
+		t.Errorf("Open returned %q (%d bytes)", data, size)
+	}
+
+	if err := storage.Delete(key); err != nil {
+		t.Fatal("Delete:", err)
+	}
+	if _, _, err := storage.Open(key); !errors.Is(err, ErrStoredFileNotFound) {
+		t.Errorf("Open after Delete: got %v, want ErrStoredFileNotFound", err)
+	}
+	// Deleting a missing object is not an error, as on S3
+	if err := storage.Delete(key); err != nil {
+		t.Errorf("Delete of a missing object: %v", err)
+	}
+}
+
+func TestS3StorageErrors(t *testing.T) {
+	fake, srv := newFakeS3(t)
+	storage, err := NewS3Storage(S3Config{
+		Endpoint: srv.URL, Region: fake.region, Bucket: "helpdesk",
+		AccessKey: fake.accessKey, SecretKey: "wrong",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = storage.Put("a.txt", strings.NewReader("x"), 1, "text/plain")
+	if err == nil || errors.Is(err, ErrStoredFileNotFound) || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Put with a bad signature: got %v, want a 403 error", err)
+	}
+	if _, _, err := storage.Open("missing.txt"); err == nil || errors.Is(err, ErrStoredFileNotFound) {
+		t.Errorf("Open with a bad signature: got %v, want a 403 error", err)
+	}
+
+	if _, err := NewS3Storage(S3Config{Endpoint: srv.URL, Bucket: "helpdesk"}); err == nil {
+		t.Error("NewS3Storage accepted a config without credentials")
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"database/sql"
+
+	"helpdesk-backend/config"
+)
+
+// TicketChatID returns the Telegram chat of the ticket's team, or "" for the default chat
+func TicketChatID(ticketID int) (string, error) {
+	var chatID *string
+	err := config.DB.QueryRow(`
+		SELECT tm.telegram_chat_id
+		FROM helpdesk_tickets t
+		JOIN helpdesk_teams tm ON tm.id = t.team_id
+		WHERE t.id = ?
+	`, ticketID).Scan(&chatID)
+	if err == sql.ErrNoRows || (err == nil && chatID == nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return *chatID, nil
+}
+
+// CategoryTeamID returns the default team of a category, inherited from the
+// nearest parent category when the category itself has none
+func CategoryTeamID(categoryID int) (*int, error) {
+	seen := map[int]bool{}
+	current := &categoryID
+	for current != nil && !seen[*current] {
+		seen[*current] = true
+
+		var teamID, parentID *int
+		err := config.DB.QueryRow(`
+			SELECT team_id, parent_id FROM helpdesk_categories WHERE id = ?
+		`, *current).Scan(&teamID, &parentID)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if teamID != nil {
+			return teamID, nil
+		}
+		current = parentID
+	}
+	return nil, nil
+}
+
+// TeamMemberIDs returns the user IDs belonging to a team
+func TeamMemberIDs(teamID int) ([]string, error) {
+	rows, err := config.DB.Query(`
+		SELECT user_id FROM helpdesk_team_members WHERE team_id = ? ORDER BY user_id
+	`, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []string{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		members = append(members, userID)
+	}
+	return members, nil
+}
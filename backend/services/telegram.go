@@ -3,82 +3,189 @@ package services
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
+	"io"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 )
 
 type TelegramMessage struct {
-	ChatID    string `json:"chat_id"`
-	Text      string `json:"text"`
-	ParseMode string `json:"parse_mode"`
+	ChatID      string                  `json:"chat_id"`
+	Text        string                  `json:"text"`
+	ParseMode   string                  `json:"parse_mode"`
+	ReplyMarkup *TelegramInlineKeyboard `json:"reply_markup,omitempty"`
 }
 
-// SendTelegramNotification sends a message to Telegram
+// TelegramInlineKeyboard is a row-wise grid of buttons attached to a message
+type TelegramInlineKeyboard struct {
+	InlineKeyboard [][]TelegramInlineButton `json:"inline_keyboard"`
+}
+
+// TelegramInlineButton sends CallbackData back to the bot when pressed
+type TelegramInlineButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+// SendTelegramNotification sends a message to the default Telegram chat
 func SendTelegramNotification(message string) error {
+	return SendTelegramNotificationTo("", message)
+}
+
+// SendTelegramNotificationTo sends a message to a Telegram chat, falling back to
+// TELEGRAM_CHAT_ID when chatID is empty. Errors are those of callTelegram.
+func SendTelegramNotificationTo(chatID string, message string) error {
+	return SendTelegramMessage(TelegramMessage{ChatID: chatID, Text: message})
+}
+
+// SendTelegramMessage sends an HTML message, optionally with inline buttons, falling
+// back to TELEGRAM_CHAT_ID when msg.ChatID is empty
+func SendTelegramMessage(msg TelegramMessage) error {
 	botToken := os.Getenv("TELEGRAM_BOT_TOKEN")
-	chatID := os.Getenv("TELEGRAM_CHAT_ID")
+	if msg.ChatID == "" {
+		msg.ChatID = os.Getenv("TELEGRAM_CHAT_ID")
+	}
 
-	if botToken == "" || chatID == "" {
+	if botToken == "" || msg.ChatID == "" {
 		// Skip if not configured
 		return nil
 	}
 
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", botToken)
-
-	msg := TelegramMessage{
-		ChatID:    chatID,
-		Text:      message,
-		ParseMode: "HTML",
-	}
+	msg.ParseMode = "HTML"
+	return callTelegram(telegramClient, botToken, "sendMessage", msg, nil)
+}
 
-	jsonData, err := json.Marshal(msg)
+// callTelegram calls a Bot API method and decodes its result into out (if not nil).
+// Rate limits are returned as a DeliveryError carrying Telegram's retry_after; other
+// client errors are permanent. A 409 Conflict wraps errTelegramConflict.
+func callTelegram(client *http.Client, botToken, method string, payload interface{}, out interface{}) error {
+	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	resp, err := client.Post(telegramAPIURL(botToken, method), "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	return nil
+	var result telegramResponse
+	json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result)
+	if resp.StatusCode == http.StatusOK && result.OK {
+		if out != nil {
+			return json.Unmarshal(result.Result, out)
+		}
+		return nil
+	}
+
+	if result.Description == "" {
+		result.Description = resp.Status
+	}
+	err = fmt.Errorf("telegram: %s", result.Description)
+	switch {
+	case resp.StatusCode == http.StatusConflict:
+		return &DeliveryError{Err: fmt.Errorf("%w: %s", errTelegramConflict, result.Description), Permanent: true}
+	case result.Parameters.RetryAfter > 0:
+		return &DeliveryError{Err: err, RetryAfter: time.Duration(result.Parameters.RetryAfter) * time.Second}
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests:
+		return &DeliveryError{Err: err, Permanent: true}
+	}
+	return err
+}
+
+// errTelegramConflict is returned when getUpdates conflicts with another poller or a webhook
+var errTelegramConflict = errors.New("telegram: conflict")
+
+var telegramClient = &http.Client{Timeout: 15 * time.Second}
+
+// telegramResponse is the envelope of every Bot API response
+type telegramResponse struct {
+	OK          bool            `json:"ok"`
+	Description string          `json:"description"`
+	Result      json.RawMessage `json:"result"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
 }
 
-// NotifyNewTicket sends notification for new ticket
-func NotifyNewTicket(ticketNumber, subject, category, userName string) {
-	message := fmt.Sprintf(
-		"🆕 <b>Tiket Baru!</b>\n\n"+
-			"📋 <b>No:</b> %s\n"+
-			"📝 <b>Subject:</b> %s\n"+
-			"📁 <b>Kategori:</b> %s\n"+
-			"👤 <b>Dari:</b> %s",
-		ticketNumber, subject, category, userName,
-	)
-	SendTelegramNotification(message)
+// telegramAPIURL builds a Bot API method URL. TELEGRAM_API_URL overrides the API
+// server, e.g. a local Bot API server or a fake in tests.
+func telegramAPIURL(botToken, method string) string {
+	base := os.Getenv("TELEGRAM_API_URL")
+	if base == "" {
+		base = "https://api.telegram.org"
+	}
+	return fmt.Sprintf("%s/bot%s/%s", strings.TrimRight(base, "/"), botToken, method)
 }
 
-// NotifyStatusChange sends notification for status change
-func NotifyStatusChange(ticketNumber, subject, oldStatus, newStatus, handledBy string) {
-	statusEmoji := map[string]string{
-		"dikerjakan": "🔄",
-		"selesai":    "✅",
-		"ditutup":    "🔒",
+// telegramNotifier sends notifications to the ticket team's Telegram chat, or the
+// default TELEGRAM_CHAT_ID for admin alerts and teams without a chat
+type telegramNotifier struct{}
+
+// NewTelegramNotifier returns the Telegram notification channel
+func NewTelegramNotifier() Notifier {
+	return telegramNotifier{}
+}
+
+func (telegramNotifier) Name() string { return "telegram" }
+
+func (telegramNotifier) Notify(n Notification) error {
+	chatID := ""
+	if n.TicketID != 0 && !n.AdminAlert {
+		// Retried by the outbox rather than sent to the default chat
+		id, err := TicketChatID(n.TicketID)
+		if err != nil {
+			return fmt.Errorf("team chat of ticket %d: %w", n.TicketID, err)
+		}
+		chatID = id
+	}
+	// Staff can pick up new tickets straight from the chat
+	msg := TelegramMessage{ChatID: chatID, Text: FormatTelegramMessage(n)}
+	if n.Event == NotificationTicketCreated && n.TicketID != 0 && TelegramBotEnabled() {
+		// The ticket may have been auto-assigned since the notification was queued
+		if t, err := loadTelegramTicket(n.TicketID); err == nil {
+			msg.ReplyMarkup = ticketKeyboard(t, nil)
+		}
 	}
+	return SendTelegramMessage(msg)
+}
+
+// telegramPersonalNotifier sends personal notifications to the private chat the
+// recipient linked through the bot. Recipients who unlinked are skipped.
+type telegramPersonalNotifier struct{}
+
+// NewTelegramPersonalNotifier returns the personal Telegram notification channel
+func NewTelegramPersonalNotifier() Notifier {
+	return telegramPersonalNotifier{}
+}
+
+func (telegramPersonalNotifier) Name() string { return "telegram_personal" }
 
-	emoji := statusEmoji[newStatus]
-	if emoji == "" {
-		emoji = "📋"
+func (telegramPersonalNotifier) Notify(n Notification) error {
+	chatID := TelegramChatForUser(n.RecipientID)
+	if chatID == "" {
+		return nil
 	}
+	return SendTelegramNotificationTo(chatID, FormatTelegramMessage(n))
+}
 
-	message := fmt.Sprintf(
-		"%s <b>Status Tiket Berubah!</b>\n\n"+
-			"📋 <b>No:</b> %s\n"+
-			"📝 <b>Subject:</b> %s\n"+
-			"📊 <b>Status:</b> %s → <b>%s</b>\n"+
-			"👷 <b>Dikerjakan:</b> %s",
-		emoji, ticketNumber, subject, oldStatus, newStatus, handledBy,
-	)
-	SendTelegramNotification(message)
+// FormatTelegramMessage renders a notification as Telegram HTML
+func FormatTelegramMessage(n Notification) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s <b>%s</b>\n", n.Icon, html.EscapeString(n.Title))
+	for i, f := range n.Fields {
+		if i == 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s <b>%s:</b> %s\n", f.Icon, html.EscapeString(f.Label), html.EscapeString(f.Value))
+	}
+	if n.Body != "" {
+		b.WriteString("\n" + html.EscapeString(n.Body))
+	}
+	return strings.TrimRight(b.String(), "\n")
 }
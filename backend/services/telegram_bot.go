@@ -0,0 +1,216 @@
+package services
+
+import (
+	"errors"
+	"html"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TelegramUpdate is an incoming Bot API update, from getUpdates or the webhook
+type TelegramUpdate struct {
+	UpdateID      int64                    `json:"update_id"`
+	Message       *TelegramIncomingMessage `json:"message"`
+	CallbackQuery *TelegramCallbackQuery   `json:"callback_query"`
+}
+
+// TelegramIncomingMessage is a message sent to the bot
+type TelegramIncomingMessage struct {
+	MessageID int64         `json:"message_id"`
+	From      *TelegramUser `json:"from"`
+	Chat      TelegramChat  `json:"chat"`
+	Text      string        `json:"text"`
+}
+
+// TelegramCallbackQuery is a press on an inline button
+type TelegramCallbackQuery struct {
+	ID      string                   `json:"id"`
+	From    TelegramUser             `json:"from"`
+	Message *TelegramIncomingMessage `json:"message"`
+	Data    string                   `json:"data"`
+}
+
+type TelegramUser struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"first_name"`
+}
+
+type TelegramChat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+}
+
+// telegramCommand handles a bot command; args is the text after the command
+type telegramCommand func(msg *TelegramIncomingMessage, args string) error
+
+var telegramCommands = map[string]telegramCommand{
+	"start":   startCommand,
+	"stop":    stopCommand,
+	"bantuan": helpCommand,
+	"antrian": queueCommand,
+	"tiket":   ticketCommand,
+	"saya":    myTicketsCommand,
+}
+
+// HandleTelegramUpdate runs the bot command or button press in an update, if any
+func HandleTelegramUpdate(update TelegramUpdate) error {
+	if update.CallbackQuery != nil {
+		return handleTicketButton(update.CallbackQuery)
+	}
+
+	msg := update.Message
+	if msg == nil || !strings.HasPrefix(msg.Text, "/") {
+		return nil
+	}
+
+	name, args, _ := strings.Cut(strings.TrimPrefix(msg.Text, "/"), " ")
+	// Commands in groups are addressed as /command@botname
+	name, _, _ = strings.Cut(strings.ToLower(name), "@")
+	command, ok := telegramCommands[name]
+	if !ok {
+		return nil
+	}
+	return command(msg, strings.TrimSpace(args))
+}
+
+func replyTelegram(msg *TelegramIncomingMessage, text string) error {
+	return SendTelegramNotificationTo(strconv.FormatInt(msg.Chat.ID, 10), text)
+}
+
+// startCommand links the private chat to the helpdesk user who generated the code
+func startCommand(msg *TelegramIncomingMessage, code string) error {
+	if msg.Chat.Type != "private" {
+		return nil
+	}
+	if code == "" {
+		return replyTelegram(msg, "Buka menu Telegram di aplikasi Helpdesk untuk menautkan akun Anda.")
+	}
+
+	username := ""
+	if msg.From != nil {
+		username = msg.From.Username
+	}
+	if _, err := LinkTelegramChat(code, strconv.FormatInt(msg.Chat.ID, 10), username); err != nil {
+		if errors.Is(err, ErrTelegramLinkInvalid) {
+			return replyTelegram(msg, html.EscapeString(err.Error()))
+		}
+		return err
+	}
+	return replyTelegram(msg, "✅ Akun Telegram berhasil ditautkan. Anda akan menerima kabar tentang tiket Anda di sini.\n\nKirim /stop untuk berhenti.")
+}
+
+// stopCommand unlinks the chat
+func stopCommand(msg *TelegramIncomingMessage, _ string) error {
+	if msg.Chat.Type != "private" {
+		return nil
+	}
+	unlinked, err := UnlinkTelegramChat(strconv.FormatInt(msg.Chat.ID, 10))
+	if err != nil {
+		return err
+	}
+	if !unlinked {
+		return replyTelegram(msg, "Chat ini tidak tertaut ke akun Helpdesk.")
+	}
+	return replyTelegram(msg, "🔕 Tautan akun Telegram dihapus. Anda tidak akan menerima notifikasi lagi.")
+}
+
+func helpCommand(msg *TelegramIncomingMessage, _ string) error {
+	return replyTelegram(msg, `<b>Perintah Helpdesk</b>
+
+/antrian - tiket terbuka yang belum diambil
+/tiket HD-20260101-001 - detail tiket
+/saya - tiket yang ditugaskan kepada Anda
+/stop - hentikan notifikasi pribadi
+
+Perintah staf memerlukan akun Telegram yang sudah ditautkan di aplikasi Helpdesk.`)
+}
+
+// TelegramBotEnabled reports whether the bot receives updates, so inline buttons work
+func TelegramBotEnabled() bool {
+	mode := os.Getenv("TELEGRAM_BOT_MODE")
+	return os.Getenv("TELEGRAM_BOT_TOKEN") != "" && (mode == "polling" || mode == "webhook")
+}
+
+// TelegramWebhookSecret is the secret Telegram sends with webhook updates
+// (X-Telegram-Bot-Api-Secret-Token)
+func TelegramWebhookSecret() string {
+	return os.Getenv("TELEGRAM_WEBHOOK_SECRET")
+}
+
+// StartTelegramBot receives bot updates according to TELEGRAM_BOT_MODE: "off" (default)
+// only sends notifications, "polling" long-polls getUpdates and "webhook" registers
+// TELEGRAM_WEBHOOK_URL and receives updates on the webhook route. Only one instance may
+// poll a bot, and not while a webhook is set.
+func StartTelegramBot() {
+	botToken := os.Getenv("TELEGRAM_BOT_TOKEN")
+	if botToken == "" {
+		return
+	}
+
+	switch mode := os.Getenv("TELEGRAM_BOT_MODE"); mode {
+	case "polling":
+		go pollTelegramUpdates(botToken)
+	case "webhook":
+		if TelegramWebhookSecret() == "" {
+			log.Fatal("TELEGRAM_WEBHOOK_SECRET is required in webhook mode")
+		}
+		if webhookURL := os.Getenv("TELEGRAM_WEBHOOK_URL"); webhookURL != "" {
+			err := callTelegram(telegramClient, botToken, "setWebhook", map[string]interface{}{
+				"url":             webhookURL,
+				"secret_token":    TelegramWebhookSecret(),
+				"allowed_updates": telegramAllowedUpdates,
+			}, nil)
+			if err != nil {
+				log.Println("Failed to register Telegram webhook:", err)
+			}
+		}
+	case "", "off":
+	default:
+		log.Fatalf("Unknown TELEGRAM_BOT_MODE %q", mode)
+	}
+}
+
+var telegramAllowedUpdates = []string{"message", "callback_query"}
+
+// telegramPollTimeout is the long-polling timeout of getUpdates, in seconds
+const telegramPollTimeout = 50
+
+func pollTelegramUpdates(botToken string) {
+	client := &http.Client{Timeout: (telegramPollTimeout + 10) * time.Second}
+	var offset int64
+	for {
+		var updates []TelegramUpdate
+		err := callTelegram(client, botToken, "getUpdates", map[string]interface{}{
+			"offset":          offset,
+			"timeout":         telegramPollTimeout,
+			"allowed_updates": telegramAllowedUpdates,
+		}, &updates)
+		if errors.Is(err, errTelegramConflict) {
+			// Retrying would only fight the other poller or the webhook for updates
+			log.Println("Telegram polling stopped:", err)
+			return
+		}
+		if err != nil {
+			log.Println("Telegram getUpdates failed:", err)
+			wait := 5 * time.Second
+			var de *DeliveryError
+			if errors.As(err, &de) && de.RetryAfter > 0 {
+				wait = de.RetryAfter
+			}
+			time.Sleep(wait)
+			continue
+		}
+
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+			if err := HandleTelegramUpdate(update); err != nil {
+				log.Println("Telegram update failed:", err)
+			}
+		}
+	}
+}
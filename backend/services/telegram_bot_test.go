@@ -0,0 +1,56 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTelegramBotEnabled(t *testing.T) {
+	tests := []struct {
+		token, mode string
+		want        bool
+	}{
+		{"123:abc", "", false},
+		{"123:abc", "off", false},
+		{"123:abc", "polling", true},
+		{"123:abc", "webhook", true},
+		{"", "polling", false},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("TELEGRAM_BOT_TOKEN", tt.token)
+		t.Setenv("TELEGRAM_BOT_MODE", tt.mode)
+		if got := TelegramBotEnabled(); got != tt.want {
+			t.Errorf("TELEGRAM_BOT_MODE=%q: TelegramBotEnabled() = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestPollTelegramUpdatesStopsOnConflict(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"ok":false,"error_code":409,"description":"Conflict: terminated by other getUpdates request"}`))
+	}))
+	defer srv.Close()
+	t.Setenv("TELEGRAM_API_URL", srv.URL)
+
+	done := make(chan struct{})
+	go func() {
+		pollTelegramUpdates("123:abc")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("polling kept going after a 409 Conflict")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("getUpdates called %d times, want 1", calls.Load())
+	}
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"helpdesk-backend/config"
+	"helpdesk-backend/models"
+)
+
+var (
+	ErrTelegramNotConfigured = errors.New("Bot Telegram belum dikonfigurasi")
+	ErrTelegramLinkInvalid   = errors.New("Kode tautan Telegram tidak valid atau sudah kedaluwarsa")
+)
+
+// telegramLinkCodeTTL is how long a link code can be redeemed, in minutes
+const telegramLinkCodeTTL = 15
+
+// CreateTelegramLinkCode generates a one-time code and the bot deep link that
+// redeems it. Older codes of the user stop working.
+func CreateTelegramLinkCode(userID string) (*models.TelegramLinkCode, error) {
+	botName, err := telegramBotUsername()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	code := hex.EncodeToString(buf)
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM helpdesk_telegram_link_codes WHERE user_id = ? OR expires_at < NOW()`, userID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO helpdesk_telegram_link_codes (code, user_id, expires_at)
+		VALUES (?, ?, NOW() + INTERVAL ? MINUTE)
+	`, code, userID, telegramLinkCodeTTL); err != nil {
+		return nil, err
+	}
+
+	link := models.TelegramLinkCode{
+		Code: code,
+		URL:  "https://t.me/" + url.PathEscape(botName) + "?start=" + code,
+	}
+	if err := tx.QueryRow(`SELECT expires_at FROM helpdesk_telegram_link_codes WHERE code = ?`, code).Scan(&link.ExpiresAt); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// LinkTelegramChat redeems a link code for a private chat and returns the linked user.
+// A chat belongs to one user at a time; linking it again moves it.
+func LinkTelegramChat(code, chatID, username string) (string, error) {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var userID string
+	err = tx.QueryRow(`
+		SELECT user_id FROM helpdesk_telegram_link_codes WHERE code = ? AND expires_at > NOW() FOR UPDATE
+	`, code).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return "", ErrTelegramLinkInvalid
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM helpdesk_telegram_link_codes WHERE user_id = ?`, userID); err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec(`DELETE FROM helpdesk_telegram_links WHERE chat_id = ? AND user_id <> ?`, chatID, userID); err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO helpdesk_telegram_links (user_id, chat_id, telegram_username) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE chat_id = VALUES(chat_id), telegram_username = VALUES(telegram_username), linked_at = NOW()
+	`, userID, chatID, username); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return userID, nil
+}
+
+// GetTelegramLink returns the Telegram account linked to a user
+func GetTelegramLink(userID string) (*models.TelegramLink, error) {
+	link := models.TelegramLink{}
+	err := config.DB.QueryRow(`
+		SELECT telegram_username, linked_at FROM helpdesk_telegram_links WHERE user_id = ?
+	`, userID).Scan(&link.TelegramUsername, &link.LinkedAt)
+	if err == sql.ErrNoRows {
+		return &link, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	link.Linked = true
+	return &link, nil
+}
+
+// UnlinkTelegram removes a user's linked Telegram account
+func UnlinkTelegram(userID string) error {
+	_, err := config.DB.Exec(`DELETE FROM helpdesk_telegram_links WHERE user_id = ?`, userID)
+	return err
+}
+
+// UnlinkTelegramChat removes the link of a Telegram chat, reporting whether one existed
+func UnlinkTelegramChat(chatID string) (bool, error) {
+	result, err := config.DB.Exec(`DELETE FROM helpdesk_telegram_links WHERE chat_id = ?`, chatID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// TelegramChatForUser returns the private chat linked to a user, or "" if none
+func TelegramChatForUser(userID string) string {
+	var chatID string
+	config.DB.QueryRow(`SELECT chat_id FROM helpdesk_telegram_links WHERE user_id = ?`, userID).Scan(&chatID)
+	return chatID
+}
+
+var botUsername struct {
+	sync.Mutex
+	name string
+}
+
+// telegramBotUsername returns TELEGRAM_BOT_USERNAME, or asks the Bot API once
+func telegramBotUsername() (string, error) {
+	if name := strings.TrimPrefix(os.Getenv("TELEGRAM_BOT_USERNAME"), "@"); name != "" {
+		return name, nil
+	}
+	botToken := os.Getenv("TELEGRAM_BOT_TOKEN")
+	if botToken == "" {
+		return "", ErrTelegramNotConfigured
+	}
+
+	botUsername.Lock()
+	defer botUsername.Unlock()
+	if botUsername.name == "" {
+		var me TelegramUser
+		if err := callTelegram(telegramClient, botToken, "getMe", struct{}{}, &me); err != nil {
+			return "", err
+		}
+		botUsername.name = me.Username
+	}
+	return botUsername.name, nil
+}
@@ -0,0 +1,401 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"helpdesk-backend/config"
+)
+
+var (
+	ErrTelegramNotStaff  = errors.New("Akun Telegram Anda belum ditautkan ke staf Helpdesk. Tautkan melalui aplikasi Helpdesk.")
+	errTelegramBadButton = errors.New("Tombol tidak dikenal")
+)
+
+// Inline button actions, sent back as callback_data "<action>:<ticket id>"
+const (
+	buttonClaim   = "ambil"
+	buttonResolve = "selesai"
+	buttonDetail  = "detail"
+)
+
+// telegramListLimit caps the tickets listed by /antrian and /saya
+const telegramListLimit = 10
+
+// telegramUserErrors are shown to the Telegram user as is; anything else is logged
+var telegramUserErrors = []error{
+	ErrTelegramNotStaff, errTelegramBadButton, ErrTicketNotFound, ErrTicketForbidden, ErrInvalidStatus,
+	ErrTransitionNotAllowed, ErrTransitionForbidden, ErrBuktiSelesaiRequired, ErrAlreadyAssigned,
+	ErrReassignReasonRequired,
+}
+
+func isTelegramUserError(err error) bool {
+	for _, e := range telegramUserErrors {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// telegramStaff returns the staff member who linked a Telegram account. A private
+// chat has the same ID as its user, so the link of the user's chat identifies them.
+func telegramStaff(from *TelegramUser) (Staff, error) {
+	if from == nil {
+		return Staff{}, ErrTelegramNotStaff
+	}
+	var userID string
+	err := config.DB.QueryRow(`
+		SELECT user_id FROM helpdesk_telegram_links WHERE chat_id = ?
+	`, strconv.FormatInt(from.ID, 10)).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return Staff{}, ErrTelegramNotStaff
+	}
+	if err != nil {
+		return Staff{}, err
+	}
+
+	staff, err := FindStaff(userID)
+	if errors.Is(err, ErrStaffNotFound) {
+		return staff, ErrTelegramNotStaff
+	}
+	return staff, err
+}
+
+// authorizeStaffTicket applies the same checks as the HTTP API: the route's
+// permission (if any), then AuthorizeTicket for the action
+func authorizeStaffTicket(staff Staff, ticketID int, permission, action string) (TicketRef, error) {
+	if permission != "" && !RoleHasPermission(staff.Role, permission) {
+		return TicketRef{}, ErrTicketForbidden
+	}
+	ticket, err := LoadTicketRef(strconv.Itoa(ticketID))
+	if err != nil {
+		return ticket, err
+	}
+	return ticket, AuthorizeTicket(ticket, staff.UserID, staff.Role, action)
+}
+
+// staffActor is the actor of workflow changes made by staff, as in the admin handlers
+func staffActor(staff Staff) Actor {
+	return Actor{UserID: staff.UserID, Nama: staff.Nama, Role: RoleAdmin}
+}
+
+// claimTicket assigns a ticket to the staff member, like POST /tickets/:id/assign
+func claimTicket(staff Staff, ticketID int) (*StatusChange, error) {
+	if _, err := authorizeStaffTicket(staff, ticketID, PermTicketWork, TicketAssign); err != nil {
+		return nil, err
+	}
+	return AssignTicket(strconv.Itoa(ticketID), staff, "", staffActor(staff))
+}
+
+// resolveTicket marks a ticket selesai, like PATCH /admin/tickets/:id
+func resolveTicket(staff Staff, ticketID int) (*StatusChange, error) {
+	if _, err := authorizeStaffTicket(staff, ticketID, PermTicketWork, TicketWork); err != nil {
+		return nil, err
+	}
+	return ChangeTicketStatus(strconv.Itoa(ticketID), StatusSelesai, staffActor(staff))
+}
+
+// ticketKeyboard returns the buttons for a ticket in its current state. viewer is the
+// staff member the message is for, or nil for a group chat.
+func ticketKeyboard(t telegramTicket, viewer *Staff) *TelegramInlineKeyboard {
+	return &TelegramInlineKeyboard{InlineKeyboard: [][]TelegramInlineButton{ticketButtons(t, viewer, "")}}
+}
+
+// ticketButtons returns Ambil and Selesai when the workflow allows that change from the
+// ticket's status (Ambil only while nobody is assigned, Selesai only with bukti_selesai)
+// and the viewer, if known, may work on tickets, then Detail. suffix is appended to the
+// action labels.
+func ticketButtons(t telegramTicket, viewer *Staff, suffix string) []TelegramInlineButton {
+	button := func(text, action string) TelegramInlineButton {
+		return TelegramInlineButton{Text: text, CallbackData: fmt.Sprintf("%s:%d", action, t.ID)}
+	}
+	// Bot actions run as RoleAdmin, see staffActor
+	allowed := func(to string) bool {
+		if viewer != nil && !viewer.CanWorkTickets() {
+			return false
+		}
+		tr, ok := FindTransition(t.Status, to)
+		return ok && tr.allows(RoleAdmin) && (!tr.RequireBuktiSelesai || t.hasBuktiSelesai())
+	}
+
+	row := []TelegramInlineButton{}
+	// Ambil assigns the ticket, which starts work on it; reopening selesai is not a claim,
+	// and taking a ticket from its assignee needs a reason
+	if t.Status == StatusBaru && t.AssigneeID == nil && allowed(StatusDikerjakan) {
+		row = append(row, button("🙋 Ambil"+suffix, buttonClaim))
+	}
+	if allowed(StatusSelesai) {
+		row = append(row, button("✅ Selesai"+suffix, buttonResolve))
+	}
+	return append(row, button("ℹ️ Detail", buttonDetail))
+}
+
+// handleTicketButton performs the action of an inline button press
+func handleTicketButton(q *TelegramCallbackQuery) error {
+	action, id, _ := strings.Cut(q.Data, ":")
+	ticketID, err := strconv.Atoi(id)
+	if err != nil {
+		return answerTelegramButton(q, "", errTelegramBadButton)
+	}
+
+	staff, err := telegramStaff(&q.From)
+	if err != nil {
+		return answerTelegramButton(q, "", err)
+	}
+
+	var change *StatusChange
+	switch action {
+	case buttonClaim:
+		change, err = claimTicket(staff, ticketID)
+	case buttonResolve:
+		change, err = resolveTicket(staff, ticketID)
+	case buttonDetail:
+		chatID := q.From.ID
+		if q.Message != nil {
+			chatID = q.Message.Chat.ID
+		}
+		err = sendTicketDetail(staff, chatID, ticketID)
+		return answerTelegramButton(q, "", err)
+	default:
+		err = errTelegramBadButton
+	}
+	if err != nil {
+		return answerTelegramButton(q, "", err)
+	}
+
+	// Only offer the buttons that still apply
+	if t, err := loadTelegramTicket(ticketID); err == nil && q.Message != nil {
+		var viewer *Staff
+		if q.Message.Chat.Type == "private" {
+			viewer = &staff
+		}
+		callTelegramBot("editMessageReplyMarkup", map[string]interface{}{
+			"chat_id":      q.Message.Chat.ID,
+			"message_id":   q.Message.MessageID,
+			"reply_markup": ticketKeyboard(t, viewer),
+		})
+	}
+
+	text := fmt.Sprintf("Tiket %s diambil oleh %s", change.TicketNumber, change.HandledBy)
+	if change.NewStatus == StatusSelesai {
+		text = fmt.Sprintf("Tiket %s selesai", change.TicketNumber)
+	}
+	return answerTelegramButton(q, text, nil)
+}
+
+// answerTelegramButton acknowledges a button press, showing text or the error as an
+// alert. Errors not meant for the user are returned for logging.
+func answerTelegramButton(q *TelegramCallbackQuery, text string, err error) error {
+	payload := map[string]interface{}{"callback_query_id": q.ID, "text": text}
+	if err != nil {
+		payload["show_alert"] = true
+		payload["text"] = err.Error()
+		if !isTelegramUserError(err) {
+			payload["text"] = "Terjadi kesalahan, silakan coba lagi."
+		}
+	}
+
+	if answerErr := callTelegramBot("answerCallbackQuery", payload); answerErr != nil && err == nil {
+		return answerErr
+	}
+	if err != nil && !isTelegramUserError(err) {
+		return err
+	}
+	return nil
+}
+
+// replyTelegramError reports a failed command in the chat
+func replyTelegramError(msg *TelegramIncomingMessage, err error) error {
+	if isTelegramUserError(err) {
+		return replyTelegram(msg, "⚠️ "+html.EscapeString(err.Error()))
+	}
+	replyTelegram(msg, "⚠️ Terjadi kesalahan, silakan coba lagi.")
+	return err
+}
+
+func callTelegramBot(method string, payload interface{}) error {
+	botToken := os.Getenv("TELEGRAM_BOT_TOKEN")
+	if botToken == "" {
+		return nil
+	}
+	return callTelegram(telegramClient, botToken, method, payload, nil)
+}
+
+// telegramTicket is the ticket data shown by the bot
+type telegramTicket struct {
+	ID             int
+	TicketNumber   string
+	Subject        string
+	Description    string
+	Status         string
+	Category       string
+	Priority       string
+	AssigneeID     *string
+	DikerjakanOleh *string
+	BuktiSelesai   *string
+	CreatedAt      time.Time
+}
+
+func (t telegramTicket) hasBuktiSelesai() bool {
+	return t.BuktiSelesai != nil && *t.BuktiSelesai != ""
+}
+
+func loadTelegramTicket(ticketID int) (telegramTicket, error) {
+	var t telegramTicket
+	err := config.DB.QueryRow(`
+		SELECT id, ticket_number, subject, description, status, category, priority, assignee_id, dikerjakan_oleh,
+			bukti_selesai, created_at
+		FROM helpdesk_tickets WHERE id = ?
+	`, ticketID).Scan(&t.ID, &t.TicketNumber, &t.Subject, &t.Description, &t.Status, &t.Category,
+		&t.Priority, &t.AssigneeID, &t.DikerjakanOleh, &t.BuktiSelesai, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		return t, ErrTicketNotFound
+	}
+	return t, err
+}
+
+// sendTicketDetail sends a ticket the staff member may view, with its buttons
+func sendTicketDetail(staff Staff, chatID int64, ticketID int) error {
+	if _, err := authorizeStaffTicket(staff, ticketID, "", TicketView); err != nil {
+		return err
+	}
+	t, err := loadTelegramTicket(ticketID)
+	if err != nil {
+		return err
+	}
+
+	handledBy := "-"
+	if t.DikerjakanOleh != nil {
+		handledBy = *t.DikerjakanOleh
+	}
+	description := []rune(t.Description)
+	if len(description) > 1000 {
+		description = append(description[:1000], '…')
+	}
+
+	text := FormatTelegramMessage(Notification{
+		Icon:  "📋",
+		Title: "Detail Tiket",
+		Fields: []NotificationField{
+			{"📋", "No", t.TicketNumber},
+			{"📝", "Subject", t.Subject},
+			{"📁", "Kategori", t.Category},
+			{"🔥", "Prioritas", strings.ToUpper(t.Priority)},
+			{"📊", "Status", t.Status},
+			{"👷", "Dikerjakan", handledBy},
+			{"🕐", "Dibuat", t.CreatedAt.Format("02-01-2006 15:04")},
+		},
+		Body: string(description),
+	})
+	return SendTelegramMessage(TelegramMessage{
+		ChatID:      strconv.FormatInt(chatID, 10),
+		Text:        text,
+		ReplyMarkup: ticketKeyboard(t, &staff),
+	})
+}
+
+// ticketCommand shows a ticket by number: /tiket HD-20260101-001
+func ticketCommand(msg *TelegramIncomingMessage, number string) error {
+	staff, err := telegramStaff(msg.From)
+	if err != nil {
+		return replyTelegramError(msg, err)
+	}
+	if number == "" {
+		return replyTelegram(msg, "Gunakan: /tiket HD-20260101-001")
+	}
+
+	var ticketID int
+	err = config.DB.QueryRow(`
+		SELECT id FROM helpdesk_tickets WHERE ticket_number = ?
+	`, strings.ToUpper(number)).Scan(&ticketID)
+	if err == sql.ErrNoRows {
+		err = ErrTicketNotFound
+	}
+	if err == nil {
+		err = sendTicketDetail(staff, msg.Chat.ID, ticketID)
+	}
+	if err != nil {
+		return replyTelegramError(msg, err)
+	}
+	return nil
+}
+
+// queueCommand lists open tickets nobody has picked up yet: /antrian
+func queueCommand(msg *TelegramIncomingMessage, _ string) error {
+	staff, err := telegramStaff(msg.From)
+	if err == nil && !RoleHasPermission(staff.Role, PermTicketViewAll) {
+		err = ErrTicketForbidden
+	}
+	if err != nil {
+		return replyTelegramError(msg, err)
+	}
+
+	return sendTicketList(msg, staff, "📥", "Antrian Tiket", "Tidak ada tiket dalam antrian.", `
+		WHERE status IN (?, ?) AND assignee_id IS NULL`, StatusBaru, StatusDikerjakan)
+}
+
+// myTicketsCommand lists the open tickets assigned to the staff member, including
+// auto-assigned ones still in baru: /saya
+func myTicketsCommand(msg *TelegramIncomingMessage, _ string) error {
+	staff, err := telegramStaff(msg.From)
+	if err != nil {
+		return replyTelegramError(msg, err)
+	}
+
+	return sendTicketList(msg, staff, "👷", "Tiket Saya", "Tidak ada tiket yang ditugaskan kepada Anda.", `
+		WHERE status IN (?, ?) AND assignee_id = ?`, StatusBaru, StatusDikerjakan, staff.UserID)
+}
+
+// sendTicketList replies with the tickets matching where, most urgent first, each with
+// the buttons that apply to it for the staff member
+func sendTicketList(msg *TelegramIncomingMessage, staff Staff, icon, title, empty, where string, args ...interface{}) error {
+	rows, err := config.DB.Query(`
+		SELECT id, ticket_number, subject, status, priority, assignee_id, bukti_selesai FROM helpdesk_tickets`+where+`
+		ORDER BY
+			CASE priority
+				WHEN 'kritis' THEN 1
+				WHEN 'tinggi' THEN 2
+				WHEN 'sedang' THEN 3
+				WHEN 'rendah' THEN 4
+			END,
+			created_at
+		LIMIT ?
+	`, append(args, telegramListLimit)...)
+	if err != nil {
+		return replyTelegramError(msg, err)
+	}
+	defer rows.Close()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s <b>%s</b>\n", icon, html.EscapeString(title))
+	keyboard := &TelegramInlineKeyboard{InlineKeyboard: [][]TelegramInlineButton{}}
+	for rows.Next() {
+		var t telegramTicket
+		if err := rows.Scan(&t.ID, &t.TicketNumber, &t.Subject, &t.Status, &t.Priority, &t.AssigneeID, &t.BuktiSelesai); err != nil {
+			return replyTelegramError(msg, err)
+		}
+		fmt.Fprintf(&b, "\n• <b>%s</b> [%s] %s (%s)", html.EscapeString(t.TicketNumber), strings.ToUpper(t.Priority),
+			html.EscapeString(t.Subject), t.Status)
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard,
+			ticketButtons(t, &staff, " "+t.TicketNumber))
+	}
+	if err := rows.Err(); err != nil {
+		return replyTelegramError(msg, err)
+	}
+
+	if len(keyboard.InlineKeyboard) == 0 {
+		return replyTelegram(msg, empty)
+	}
+	return SendTelegramMessage(TelegramMessage{
+		ChatID:      strconv.FormatInt(msg.Chat.ID, 10),
+		Text:        b.String(),
+		ReplyMarkup: keyboard,
+	})
+}
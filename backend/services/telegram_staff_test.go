@@ -0,0 +1,366 @@
+package services
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestTicketButtons(t *testing.T) {
+	technician := &Staff{UserID: "T1", Role: RoleTechnician}
+	manager := &Staff{UserID: "M1", Role: RoleUnitManager}
+
+	tests := []struct {
+		name   string
+		ticket telegramTicket
+		viewer *Staff
+		want   []string // callback data
+	}{
+		{"baru in group chat", telegramTicket{Status: StatusBaru}, nil, []string{"ambil:7", "detail:7"}},
+		{"baru for technician", telegramTicket{Status: StatusBaru}, technician, []string{"ambil:7", "detail:7"}},
+		{"baru for unit manager", telegramTicket{Status: StatusBaru}, manager, []string{"detail:7"}},
+		{"baru auto-assigned", telegramTicket{Status: StatusBaru, AssigneeID: strPtr("T2")}, technician, []string{"detail:7"}},
+		{"dikerjakan without bukti", telegramTicket{Status: StatusDikerjakan, AssigneeID: strPtr("T1")}, technician, []string{"detail:7"}},
+		{"dikerjakan with empty bukti", telegramTicket{Status: StatusDikerjakan, BuktiSelesai: strPtr("")}, nil, []string{"detail:7"}},
+		{"dikerjakan with bukti", telegramTicket{Status: StatusDikerjakan, BuktiSelesai: strPtr("a.png")}, technician, []string{"selesai:7", "detail:7"}},
+		{"dikerjakan with bukti for unit manager", telegramTicket{Status: StatusDikerjakan, BuktiSelesai: strPtr("a.png")}, manager, []string{"detail:7"}},
+		{"selesai", telegramTicket{Status: StatusSelesai, BuktiSelesai: strPtr("a.png")}, technician, []string{"detail:7"}},
+		{"ditutup", telegramTicket{Status: StatusDitutup}, nil, []string{"detail:7"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.ticket.ID = 7
+			got := []string{}
+			for _, b := range ticketButtons(tt.ticket, tt.viewer, " HD-7") {
+				got = append(got, b.CallbackData)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("buttons %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	buttons := ticketButtons(telegramTicket{ID: 7, Status: StatusBaru}, nil, " HD-7")
+	if buttons[0].Text != "🙋 Ambil HD-7" || buttons[1].Text != "ℹ️ Detail" {
+		t.Errorf("labels %q, %q", buttons[0].Text, buttons[1].Text)
+	}
+}
+
+// Staff linked to Telegram in the bot tests, by chat ID: a technician, a second
+// technician, a unit manager (no ticket:work) and a super admin
+var botStaff = map[string][]driver.Value{
+	"1001": {"T1", "Teknisi Satu", RoleTechnician, true},
+	"1002": {"T2", "Teknisi Dua", RoleTechnician, true},
+	"1003": {"M1", "Manajer Unit", RoleUnitManager, true},
+	"1004": {"A1", "Admin Helpdesk", RoleSuperAdmin, true},
+}
+
+type botTicket struct {
+	id                    int64
+	number, status, owner string
+	assignee, handledBy   *string
+	buktiSelesai          *string
+}
+
+// Tickets of the bot tests: 1 is new, 2 was auto-assigned to T1, 3 and 4 are being
+// worked on by T1, only 4 with bukti selesai
+var botTickets = []botTicket{
+	{id: 1, number: "HD-1", status: StatusBaru, owner: "U1"},
+	{id: 2, number: "HD-2", status: StatusBaru, owner: "U1", assignee: strPtr("T1")},
+	{id: 3, number: "HD-3", status: StatusDikerjakan, owner: "U2", assignee: strPtr("T1"), handledBy: strPtr("Teknisi Satu")},
+	{id: 4, number: "HD-4", status: StatusDikerjakan, owner: "U2", assignee: strPtr("T1"), handledBy: strPtr("Teknisi Satu"), buktiSelesai: strPtr("b.png")},
+}
+
+func nullable(s *string) driver.Value {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
+func findBotTicket(id driver.Value) (botTicket, bool) {
+	for _, t := range botTickets {
+		if fmtValue(id) == fmtValue(t.id) {
+			return t, true
+		}
+	}
+	return botTicket{}, false
+}
+
+func fmtValue(v driver.Value) string {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// botQueries answers the queries of the staff commands and buttons from botStaff and botTickets
+func botQueries() []fakeQuery {
+	byID := func(columns func(t botTicket) []driver.Value) func(args []driver.Value) [][]driver.Value {
+		return func(args []driver.Value) [][]driver.Value {
+			if t, ok := findBotTicket(args[0]); ok {
+				return [][]driver.Value{columns(t)}
+			}
+			return nil
+		}
+	}
+	created := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+
+	return []fakeQuery{
+		{"FROM helpdesk_telegram_links WHERE chat_id = ?", []string{"user_id"}, func(args []driver.Value) [][]driver.Value {
+			if s, ok := botStaff[fmtValue(args[0])]; ok {
+				return [][]driver.Value{{s[0]}}
+			}
+			return nil
+		}},
+		{"FROM helpdesk_admins a", []string{"user_id", "nama", "role", "is_available"}, func(args []driver.Value) [][]driver.Value {
+			for _, s := range botStaff {
+				if s[0] == fmtValue(args[0]) {
+					return [][]driver.Value{s}
+				}
+			}
+			return nil
+		}},
+		// lockTicket
+		{"FOR UPDATE", []string{"id", "ticket_number", "subject", "status", "user_id", "assignee_id", "dikerjakan_oleh", "bukti_selesai"},
+			byID(func(t botTicket) []driver.Value {
+				return []driver.Value{t.id, t.number, "Printer " + t.number, t.status, t.owner, nullable(t.assignee),
+					nullable(t.handledBy), nullable(t.buktiSelesai)}
+			})},
+		// loadTelegramTicket
+		{"description, status, category", []string{"id", "ticket_number", "subject", "description", "status", "category",
+			"priority", "assignee_id", "dikerjakan_oleh", "bukti_selesai", "created_at"},
+			byID(func(t botTicket) []driver.Value {
+				return []driver.Value{t.id, t.number, "Printer " + t.number, "Tidak bisa mencetak", t.status, "Hardware",
+					"sedang", nullable(t.assignee), nullable(t.handledBy), nullable(t.buktiSelesai), created}
+			})},
+		// LoadTicketRef
+		{"SELECT id, ticket_number, subject, status, user_id, assignee_id", []string{"id", "ticket_number", "subject", "status", "user_id", "assignee_id"},
+			byID(func(t botTicket) []driver.Value {
+				return []driver.Value{t.id, t.number, "Printer " + t.number, t.status, t.owner, nullable(t.assignee)}
+			})},
+		// sendTicketList: status IN (?, ?), then the assignee for /saya, then the limit
+		{"bukti_selesai FROM helpdesk_tickets", []string{"id", "ticket_number", "subject", "status", "priority", "assignee_id", "bukti_selesai"},
+			func(args []driver.Value) [][]driver.Value {
+				rows := [][]driver.Value{}
+				for _, t := range botTickets {
+					if t.status != args[0] && t.status != args[1] {
+						continue
+					}
+					if len(args) == 3 && t.assignee != nil || len(args) == 4 && (t.assignee == nil || *t.assignee != args[2]) {
+						continue
+					}
+					rows = append(rows, []driver.Value{t.id, t.number, "Printer " + t.number, t.status, "sedang",
+						nullable(t.assignee), nullable(t.buktiSelesai)})
+				}
+				return rows
+			}},
+		{"WHERE ticket_number = ?", []string{"id"}, func(args []driver.Value) [][]driver.Value {
+			for _, t := range botTickets {
+				if t.number == args[0] {
+					return [][]driver.Value{{t.id}}
+				}
+			}
+			return nil
+		}},
+	}
+}
+
+// botAPICall is a Bot API request received by the fake server
+type botAPICall struct {
+	method  string
+	payload map[string]interface{}
+}
+
+// fakeBotAPI points the bot at a local Bot API server that accepts every call and
+// records it
+func fakeBotAPI(t *testing.T) func() []botAPICall {
+	var mu sync.Mutex
+	calls := []botAPICall{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := botAPICall{method: r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]}
+		json.NewDecoder(r.Body).Decode(&call.payload)
+		mu.Lock()
+		calls = append(calls, call)
+		mu.Unlock()
+		w.Write([]byte(`{"ok":true,"result":true}`))
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("TELEGRAM_API_URL", srv.URL)
+	t.Setenv("TELEGRAM_BOT_TOKEN", "123:abc")
+
+	return func() []botAPICall {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]botAPICall{}, calls...)
+	}
+}
+
+// keyboardData returns the callback data of each row of a sent inline keyboard
+func keyboardData(payload map[string]interface{}) []string {
+	markup, _ := payload["reply_markup"].(map[string]interface{})
+	rows, _ := markup["inline_keyboard"].([]interface{})
+	data := []string{}
+	for _, row := range rows {
+		buttons := []string{}
+		for _, b := range row.([]interface{}) {
+			buttons = append(buttons, b.(map[string]interface{})["callback_data"].(string))
+		}
+		data = append(data, strings.Join(buttons, " "))
+	}
+	return data
+}
+
+func TestTelegramStaffCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		chatID   int64
+		text     string
+		contains []string
+		excludes []string
+		keyboard []string
+	}{
+		{"antrian for technician", 1001, "/antrian", []string{"Antrian Tiket", "HD-1"}, []string{"HD-2", "HD-3"},
+			[]string{"ambil:1 detail:1"}},
+		{"antrian for unit manager", 1003, "/antrian", []string{"HD-1"}, nil, []string{"detail:1"}},
+		{"antrian for unlinked chat", 9999, "/antrian", []string{"belum ditautkan"}, nil, nil},
+		{"saya", 1001, "/saya", []string{"Tiket Saya", "HD-2", "HD-3", "HD-4"}, []string{"HD-1"},
+			[]string{"detail:2", "detail:3", "selesai:4 detail:4"}},
+		{"saya without tickets", 1002, "/saya", []string{"Tidak ada tiket"}, nil, nil},
+		{"tiket", 1001, "/tiket hd-4", []string{"Detail Tiket", "HD-4", "Teknisi Satu"}, nil, []string{"selesai:4 detail:4"}},
+		{"tiket for unit manager", 1003, "/tiket HD-4", []string{"HD-4"}, nil, []string{"detail:4"}},
+		{"tiket unknown", 1001, "/tiket HD-404", []string{ErrTicketNotFound.Error()}, nil, nil},
+		{"tiket without number", 1001, "/tiket", []string{"Gunakan: /tiket"}, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeDB(t, botQueries()...)
+			calls := fakeBotAPI(t)
+
+			msg := &TelegramIncomingMessage{From: &TelegramUser{ID: tt.chatID}, Chat: TelegramChat{ID: tt.chatID, Type: "private"}, Text: tt.text}
+			if err := HandleTelegramUpdate(TelegramUpdate{Message: msg}); err != nil {
+				t.Fatal(err)
+			}
+
+			sent := calls()
+			if len(sent) != 1 || sent[0].method != "sendMessage" {
+				t.Fatalf("Bot API calls %+v, want one sendMessage", sent)
+			}
+			text, _ := sent[0].payload["text"].(string)
+			for _, s := range tt.contains {
+				if !strings.Contains(text, s) {
+					t.Errorf("reply %q does not contain %q", text, s)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(text, s) {
+					t.Errorf("reply %q contains %q", text, s)
+				}
+			}
+			if got := keyboardData(sent[0].payload); strings.Join(got, "|") != strings.Join(tt.keyboard, "|") {
+				t.Errorf("keyboard %v, want %v", got, tt.keyboard)
+			}
+		})
+	}
+}
+
+func TestTelegramStaffButtons(t *testing.T) {
+	tests := []struct {
+		name    string
+		chatID  int64
+		data    string
+		alert   string // error shown to the user, empty on success
+		answer  string
+		updates int // UPDATE helpdesk_tickets statements run
+	}{
+		{"technician claims a new ticket", 1001, "ambil:1", "", "Tiket HD-1 diambil oleh Teknisi Satu", 1},
+		{"unit manager may not claim", 1003, "ambil:1", ErrTicketForbidden.Error(), "", 0},
+		{"unit manager may not resolve", 1003, "selesai:4", ErrTicketForbidden.Error(), "", 0},
+		{"technician may not take another's ticket", 1002, "ambil:2", ErrTicketForbidden.Error(), "", 0},
+		{"admin needs a reason to take an auto-assigned ticket", 1004, "ambil:2", ErrReassignReasonRequired.Error(), "", 0},
+		{"unlinked chat", 9999, "ambil:1", ErrTelegramNotStaff.Error(), "", 0},
+		{"unknown action", 1001, "hapus:1", errTelegramBadButton.Error(), "", 0},
+		{"bad ticket ID", 1001, "ambil:x", errTelegramBadButton.Error(), "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := useFakeDB(t, botQueries()...)
+			calls := fakeBotAPI(t)
+
+			q := &TelegramCallbackQuery{
+				ID:      "cb1",
+				From:    TelegramUser{ID: tt.chatID},
+				Message: &TelegramIncomingMessage{MessageID: 55, Chat: TelegramChat{ID: tt.chatID, Type: "private"}},
+				Data:    tt.data,
+			}
+			if err := HandleTelegramUpdate(TelegramUpdate{CallbackQuery: q}); err != nil {
+				t.Fatal(err)
+			}
+
+			sent := calls()
+			answer := sent[len(sent)-1]
+			if answer.method != "answerCallbackQuery" || answer.payload["callback_query_id"] != "cb1" {
+				t.Fatalf("last Bot API call %+v, want answerCallbackQuery", answer)
+			}
+			if tt.alert != "" {
+				if answer.payload["show_alert"] != true || answer.payload["text"] != tt.alert {
+					t.Errorf("answer %v, want alert %q", answer.payload, tt.alert)
+				}
+			} else if answer.payload["show_alert"] != nil || answer.payload["text"] != tt.answer {
+				t.Errorf("answer %v, want %q", answer.payload, tt.answer)
+			}
+
+			updates := db.execsMatching("UPDATE helpdesk_tickets")
+			if len(updates) != tt.updates {
+				t.Fatalf("%d ticket updates, want %d", len(updates), tt.updates)
+			}
+			if tt.updates > 0 {
+				// status, assignee_id, dikerjakan_oleh, id
+				if args := updates[0].args; args[0] != StatusDikerjakan || args[1] != "T1" || args[2] != "Teknisi Satu" {
+					t.Errorf("update args %v", args)
+				}
+				if sent[0].method != "editMessageReplyMarkup" || sent[0].payload["message_id"] != float64(55) {
+					t.Errorf("Bot API calls %+v, want the keyboard refreshed first", sent)
+				}
+			}
+		})
+	}
+}
+
+func TestTelegramDetailButton(t *testing.T) {
+	useFakeDB(t, botQueries()...)
+	calls := fakeBotAPI(t)
+
+	q := &TelegramCallbackQuery{
+		ID:      "cb2",
+		From:    TelegramUser{ID: 1003},
+		Message: &TelegramIncomingMessage{Chat: TelegramChat{ID: -500, Type: "group"}},
+		Data:    "detail:4",
+	}
+	if err := HandleTelegramUpdate(TelegramUpdate{CallbackQuery: q}); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := calls()
+	if len(sent) != 2 || sent[0].method != "sendMessage" || sent[1].method != "answerCallbackQuery" {
+		t.Fatalf("Bot API calls %+v", sent)
+	}
+	// The detail goes to the chat the button was in, with the presser's buttons
+	if sent[0].payload["chat_id"] != "-500" {
+		t.Errorf("detail sent to %v", sent[0].payload["chat_id"])
+	}
+	if got := keyboardData(sent[0].payload); strings.Join(got, "|") != "detail:4" {
+		t.Errorf("keyboard %v", got)
+	}
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+var (
+	ErrFileEmpty             = errors.New("File kosong")
+	ErrFileTooLarge          = errors.New("Ukuran file melebihi batas")
+	ErrFileTypeNotAllowed    = errors.New("Tipe file tidak diizinkan")
+	ErrFileExtensionMismatch = errors.New("Ekstensi file tidak sesuai dengan isinya")
+)
+
+var defaultAllowedUploadTypes = []string{
+	"image/jpeg", "image/png", "image/gif", "application/pdf",
+	// Screen recordings and log bundles (usually sent as resumable uploads)
+	"video/mp4", "video/webm", "video/quicktime", "application/zip", "application/gzip", "text/plain",
+}
+
+// Extensions accepted in addition to the one mimetype reports for a type
+var extensionAliases = map[string][]string{
+	"image/jpeg": {".jpeg", ".jpe"},
+	"image/tiff": {".tif"},
+	"text/plain": {".log"},
+}
+
+// UploadedFile is a received file: a multipart form file or an assembled resumable upload
+type UploadedFile struct {
+	Name    string
+	Size    int64
+	MaxSize int64 // 0 means UPLOAD_MAX_SIZE_MB
+	Open    func() (io.ReadCloser, error)
+}
+
+// FormFile wraps a multipart form file
+func FormFile(file *multipart.FileHeader) UploadedFile {
+	return UploadedFile{
+		Name: file.Filename,
+		Size: file.Size,
+		Open: func() (io.ReadCloser, error) { return file.Open() },
+	}
+}
+
+// CheckedUpload is an upload that passed ValidateUpload
+type CheckedUpload struct {
+	MimeType  string
+	Extension string
+	SafeName  string
+}
+
+// allowedUploadTypes returns the content types accepted for attachments (UPLOAD_ALLOWED_TYPES)
+func allowedUploadTypes() []string {
+	raw := os.Getenv("UPLOAD_ALLOWED_TYPES")
+	if raw == "" {
+		return defaultAllowedUploadTypes
+	}
+	return splitList(raw)
+}
+
+// maxUploadSize returns the maximum attachment size in bytes (UPLOAD_MAX_SIZE_MB, default 10)
+func maxUploadSize() int64 {
+	mb, err := strconv.Atoi(os.Getenv("UPLOAD_MAX_SIZE_MB"))
+	if err != nil || mb <= 0 {
+		mb = 10
+	}
+	return int64(mb) << 20
+}
+
+// ValidateUpload checks the size and the sniffed content type of an upload, and that the
+// file name's extension matches the content
+func ValidateUpload(file UploadedFile) (CheckedUpload, error) {
+	if file.Size == 0 {
+		return CheckedUpload{}, ErrFileEmpty
+	}
+	max := file.MaxSize
+	if max == 0 {
+		max = maxUploadSize()
+	}
+	if file.Size > max {
+		return CheckedUpload{}, fmt.Errorf("%w (maksimal %d MB)", ErrFileTooLarge, max>>20)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return CheckedUpload{}, err
+	}
+	defer src.Close()
+
+	detected, err := mimetype.DetectReader(src)
+	if err != nil {
+		return CheckedUpload{}, err
+	}
+
+	mimeType := strings.SplitN(detected.String(), ";", 2)[0]
+
+	allowed := false
+	for _, t := range allowedUploadTypes() {
+		if detected.Is(t) {
+			allowed = true
+			break
+		}
+	}
+	// Images are only stored re-encoded, without their metadata
+	if !allowed || (strings.HasPrefix(mimeType, "image/") && !IsProcessableImage(mimeType)) {
+		return CheckedUpload{}, fmt.Errorf("%w: %s", ErrFileTypeNotAllowed, mimeType)
+	}
+
+	if max := maxImageSize(); IsProcessableImage(mimeType) && file.Size > max {
+		return CheckedUpload{}, fmt.Errorf("%w (gambar maksimal %d MB)", ErrFileTooLarge, max>>20)
+	}
+
+	ext := strings.ToLower(filepath.Ext(file.Name))
+	if ext != "" && !extensionMatches(detected, mimeType, ext) {
+		return CheckedUpload{}, fmt.Errorf("%w: %s bukan %s", ErrFileExtensionMismatch, ext, mimeType)
+	}
+
+	return CheckedUpload{
+		MimeType:  mimeType,
+		Extension: detected.Extension(),
+		SafeName:  SanitizeFilename(file.Name, detected.Extension()),
+	}, nil
+}
+
+func extensionMatches(detected *mimetype.MIME, mimeType, ext string) bool {
+	if ext == detected.Extension() {
+		return true
+	}
+	for _, alias := range extensionAliases[mimeType] {
+		if ext == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// SanitizeFilename turns a client supplied file name into a safe display name:
+// no directories or control characters, limited length, and the given extension
+func SanitizeFilename(name, ext string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.Trim(strings.TrimSpace(name), ".")
+
+	if runes := []rune(name); len(runes) > 100 {
+		name = string(runes[:100])
+	}
+	if name == "" {
+		name = "lampiran"
+	}
+	return name + ext
+}
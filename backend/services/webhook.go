@@ -0,0 +1,91 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// webhookNotifier posts notifications as JSON to one HTTP endpoint
+type webhookNotifier struct {
+	name   string
+	url    string
+	secret string
+	client *http.Client
+}
+
+// NewWebhookNotifiers returns one notification channel per endpoint, so each endpoint gets
+// its own outbox delivery and retries. Channels are named after the URL ("webhook:" + the
+// start of its SHA-256), which keeps queued deliveries with their endpoint when WEBHOOK_URLS
+// is reordered; deliveries for a removed URL fail as an unknown channel. With a secret,
+// requests carry an X-Helpdesk-Signature header: "sha256=" + hex HMAC-SHA256 of the body.
+func NewWebhookNotifiers(urls []string, secret string) ([]Notifier, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("WEBHOOK_URLS is required")
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	notifiers := []Notifier{}
+	seen := map[string]bool{}
+	for _, url := range urls {
+		if seen[url] {
+			continue
+		}
+		seen[url] = true
+		notifiers = append(notifiers, &webhookNotifier{name: webhookChannelName(url), url: url, secret: secret, client: client})
+	}
+	return notifiers, nil
+}
+
+// webhookChannelName returns the outbox channel name of an endpoint
+func webhookChannelName(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return "webhook:" + hex.EncodeToString(sum[:6])
+}
+
+func (w *webhookNotifier) Name() string { return w.name }
+
+func (w *webhookNotifier) Notify(n Notification) error {
+	// Endpoints are outside systems and never receive staff-only content
+	if n.Internal {
+		return nil
+	}
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	return w.post(w.url, body, n.Event)
+}
+
+func (w *webhookNotifier) post(url string, body []byte, event string) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Helpdesk-Event", event)
+	if w.secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.secret))
+		mac.Write(body)
+		req.Header.Set("X-Helpdesk-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook %s: %s: %s", url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWebhookNotifierSkipsInternal(t *testing.T) {
+	var posts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts.Add(1)
+	}))
+	defer srv.Close()
+
+	notifiers, err := NewWebhookNotifiers([]string{srv.URL}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	internal := testNotification()
+	internal.Internal = true
+	if err := notifiers[0].Notify(internal); err != nil {
+		t.Fatal("Notify:", err)
+	}
+	if posts.Load() != 0 {
+		t.Error("internal notification was posted")
+	}
+
+	if err := notifiers[0].Notify(testNotification()); err != nil {
+		t.Fatal("Notify:", err)
+	}
+	if posts.Load() != 1 {
+		t.Errorf("%d posts, want 1", posts.Load())
+	}
+}
+
+func TestWebhookChannelNames(t *testing.T) {
+	a, b := "https://hooks.example.com/helpdesk", "https://chat.example.com/incoming?token=x"
+	first, err := NewWebhookNotifiers([]string{a, b, a}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reordered, _ := NewWebhookNotifiers([]string{b}, "")
+
+	if len(first) != 2 {
+		t.Fatalf("%d channels for two distinct URLs", len(first))
+	}
+	if first[0].Name() == first[1].Name() {
+		t.Errorf("both endpoints are named %q", first[0].Name())
+	}
+	// The name follows the URL, not its position in WEBHOOK_URLS
+	if reordered[0].Name() != first[1].Name() {
+		t.Errorf("%s is %q after reordering, was %q", b, reordered[0].Name(), first[1].Name())
+	}
+	if name := first[0].Name(); len(name) > 30 {
+		t.Errorf("channel name %q does not fit the outbox column", name)
+	}
+}
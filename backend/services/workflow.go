@@ -0,0 +1,314 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"helpdesk-backend/config"
+)
+
+// Ticket statuses
+const (
+	StatusBaru       = "baru"
+	StatusDikerjakan = "dikerjakan"
+	StatusSelesai    = "selesai"
+	StatusDitutup    = "ditutup"
+)
+
+// Roles that can trigger a status transition
+const (
+	RoleRequester = "requester"
+	RoleAdmin     = "admin"
+)
+
+var (
+	ErrTicketNotFound         = errors.New("Ticket not found")
+	ErrInvalidStatus          = errors.New("Status tidak valid")
+	ErrTransitionNotAllowed   = errors.New("Perubahan status tidak diizinkan")
+	ErrTransitionForbidden    = errors.New("Anda tidak berhak melakukan perubahan status ini")
+	ErrBuktiSelesaiRequired   = errors.New("Bukti selesai harus diupload terlebih dahulu sebelum menyelesaikan tiket")
+	ErrAlreadyAssigned        = errors.New("Tiket sudah ditugaskan ke staf tersebut")
+	ErrReassignReasonRequired = errors.New("Alasan wajib diisi saat memindahkan tiket ke staf lain")
+)
+
+// Transition describes an allowed status change and its side effects
+type Transition struct {
+	From                string
+	To                  string
+	Roles               []string
+	RequireBuktiSelesai bool
+	SetResolvedAt       bool
+	ClearResolvedAt     bool
+	AssignIfUnassigned  bool
+}
+
+// transitions is the ticket workflow: baru → dikerjakan → selesai → ditutup,
+// plus cancelling a new ticket and reopening a resolved one.
+var transitions = []Transition{
+	{From: StatusBaru, To: StatusDikerjakan, Roles: []string{RoleAdmin}, AssignIfUnassigned: true},
+	{From: StatusBaru, To: StatusDitutup, Roles: []string{RoleRequester, RoleAdmin}},
+	{From: StatusDikerjakan, To: StatusBaru, Roles: []string{RoleAdmin}},
+	{From: StatusDikerjakan, To: StatusSelesai, Roles: []string{RoleAdmin}, RequireBuktiSelesai: true, SetResolvedAt: true, AssignIfUnassigned: true},
+	{From: StatusSelesai, To: StatusDikerjakan, Roles: []string{RoleRequester, RoleAdmin}, ClearResolvedAt: true},
+	{From: StatusSelesai, To: StatusDitutup, Roles: []string{RoleRequester, RoleAdmin}},
+}
+
+// Actor is the user performing a status change
+type Actor struct {
+	UserID string
+	Nama   string
+	Role   string
+}
+
+// StatusChange is the result of a successful transition
+type StatusChange struct {
+	TicketID     int
+	TicketNumber string
+	Subject      string
+	OldStatus    string
+	NewStatus    string
+	HandledBy    string
+}
+
+// IsValidStatus reports whether status is part of the workflow
+func IsValidStatus(status string) bool {
+	switch status {
+	case StatusBaru, StatusDikerjakan, StatusSelesai, StatusDitutup:
+		return true
+	}
+	return false
+}
+
+// FindTransition returns the transition from one status to another, if any
+func FindTransition(from, to string) (*Transition, bool) {
+	for i := range transitions {
+		if transitions[i].From == from && transitions[i].To == to {
+			return &transitions[i], true
+		}
+	}
+	return nil, false
+}
+
+func (t *Transition) allows(role string) bool {
+	for _, r := range t.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// lockedTicket is the workflow state of a ticket read inside a transaction
+type lockedTicket struct {
+	ID             int
+	TicketNumber   string
+	Subject        string
+	Status         string
+	OwnerID        string
+	AssigneeID     *string
+	DikerjakanOleh *string
+	BuktiSelesai   *string
+}
+
+func lockTicket(tx *sql.Tx, ticketID string) (*lockedTicket, error) {
+	var t lockedTicket
+	err := tx.QueryRow(`
+		SELECT id, ticket_number, subject, status, user_id, assignee_id, dikerjakan_oleh, bukti_selesai
+		FROM helpdesk_tickets WHERE id = ? FOR UPDATE
+	`, ticketID).Scan(&t.ID, &t.TicketNumber, &t.Subject, &t.Status, &t.OwnerID,
+		&t.AssigneeID, &t.DikerjakanOleh, &t.BuktiSelesai)
+	if err == sql.ErrNoRows {
+		return nil, ErrTicketNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ChangeTicketStatus validates and applies a status transition on a ticket
+func ChangeTicketStatus(ticketID string, newStatus string, actor Actor) (*StatusChange, error) {
+	if !IsValidStatus(newStatus) {
+		return nil, ErrInvalidStatus
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ticket, err := lockTicket(tx, ticketID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Requesters may only act on their own tickets
+	if actor.Role == RoleRequester && actor.UserID != ticket.OwnerID {
+		return nil, ErrTicketNotFound
+	}
+
+	t, ok := FindTransition(ticket.Status, newStatus)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s → %s", ErrTransitionNotAllowed, ticket.Status, newStatus)
+	}
+	if !t.allows(actor.Role) {
+		return nil, ErrTransitionForbidden
+	}
+	if t.RequireBuktiSelesai && (ticket.BuktiSelesai == nil || *ticket.BuktiSelesai == "") {
+		return nil, ErrBuktiSelesaiRequired
+	}
+
+	change := StatusChange{
+		TicketID:     ticket.ID,
+		TicketNumber: ticket.TicketNumber,
+		Subject:      ticket.Subject,
+		OldStatus:    ticket.Status,
+		NewStatus:    newStatus,
+	}
+
+	query := "UPDATE helpdesk_tickets SET status = ?"
+	args := []interface{}{newStatus}
+
+	// An unassigned ticket is assigned to the admin who moves it forward
+	assignActor := t.AssignIfUnassigned && ticket.AssigneeID == nil
+	if assignActor {
+		query += ", assignee_id = ?, dikerjakan_oleh = ?"
+		args = append(args, actor.UserID, actor.Nama)
+		change.HandledBy = actor.Nama
+	} else if ticket.DikerjakanOleh != nil {
+		change.HandledBy = *ticket.DikerjakanOleh
+	}
+	// Any admin action on the ticket counts as the first response
+	if actor.Role == RoleAdmin {
+		query += ", first_response_at = COALESCE(first_response_at, NOW())"
+	}
+	if t.SetResolvedAt {
+		query += ", resolved_at = NOW()"
+	}
+	if t.ClearResolvedAt {
+		query += ", resolved_at = NULL"
+	}
+
+	query += " WHERE id = ?"
+	args = append(args, ticket.ID)
+
+	if _, err := tx.Exec(query, args...); err != nil {
+		return nil, err
+	}
+
+	if err := RecordTicketEvent(tx, ticket.ID, EventStatusChanged, actor, ticket.Status, newStatus); err != nil {
+		return nil, err
+	}
+	if assignActor {
+		if err := RecordTicketEvent(tx, ticket.ID, EventAssigned, actor, "", actor.Nama); err != nil {
+			return nil, err
+		}
+	}
+	if err := NotifyStatusChange(tx, change.TicketID, change.TicketNumber, change.Subject, change.OldStatus, change.NewStatus, change.HandledBy); err != nil {
+		return nil, err
+	}
+	// The requester hears when work starts and when it is done, unless they did it
+	if actor.UserID != ticket.OwnerID && (ticket.Status == StatusBaru || newStatus == StatusSelesai) {
+		if err := NotifyRequesterStatus(tx, ticket.ID, ticket.TicketNumber, ticket.Subject, ticket.OwnerID, newStatus, change.HandledBy); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &change, nil
+}
+
+// AssignTicket assigns a ticket to a staff member, starting work on it if it is still new.
+// Moving a ticket away from another assignee requires a reason.
+func AssignTicket(ticketID string, assignee Staff, reason string, actor Actor) (*StatusChange, error) {
+	if actor.Role != RoleAdmin {
+		return nil, ErrTransitionForbidden
+	}
+	if !assignee.CanWorkTickets() {
+		return nil, ErrStaffCannotWork
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ticket, err := lockTicket(tx, ticketID)
+	if err != nil {
+		return nil, err
+	}
+
+	newStatus := ticket.Status
+	switch ticket.Status {
+	case StatusBaru:
+		t, ok := FindTransition(StatusBaru, StatusDikerjakan)
+		if !ok || !t.allows(actor.Role) {
+			return nil, ErrTransitionForbidden
+		}
+		newStatus = StatusDikerjakan
+	case StatusDikerjakan:
+		if ticket.AssigneeID != nil && *ticket.AssigneeID == assignee.UserID {
+			return nil, ErrAlreadyAssigned
+		}
+	default:
+		return nil, fmt.Errorf("%w: tiket berstatus %s", ErrTransitionNotAllowed, ticket.Status)
+	}
+	// Auto-assigned tickets are still baru, but already belong to someone
+	if ticket.AssigneeID != nil && *ticket.AssigneeID != assignee.UserID && reason == "" {
+		return nil, ErrReassignReasonRequired
+	}
+
+	_, err = tx.Exec(`
+		UPDATE helpdesk_tickets
+		SET status = ?, assignee_id = ?, dikerjakan_oleh = ?, first_response_at = COALESCE(first_response_at, NOW())
+		WHERE id = ?
+	`, newStatus, assignee.UserID, assignee.Nama, ticket.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if newStatus != ticket.Status {
+		if err := RecordTicketEvent(tx, ticket.ID, EventStatusChanged, actor, ticket.Status, newStatus); err != nil {
+			return nil, err
+		}
+	}
+	oldHandler := ""
+	if ticket.DikerjakanOleh != nil {
+		oldHandler = *ticket.DikerjakanOleh
+	}
+	if err := RecordTicketEventNote(tx, ticket.ID, EventAssigned, actor, oldHandler, assignee.Nama, reason); err != nil {
+		return nil, err
+	}
+	if err := NotifyTicketAssigned(tx, ticket.ID, ticket.TicketNumber, ticket.Subject, assignee.Nama, actor.Nama, reason); err != nil {
+		return nil, err
+	}
+	if assignee.UserID != actor.UserID {
+		if err := NotifyAssignee(tx, ticket.ID, ticket.TicketNumber, ticket.Subject, assignee.UserID, actor.Nama, reason); err != nil {
+			return nil, err
+		}
+	}
+	if newStatus != ticket.Status && actor.UserID != ticket.OwnerID {
+		if err := NotifyRequesterStatus(tx, ticket.ID, ticket.TicketNumber, ticket.Subject, ticket.OwnerID, newStatus, assignee.Nama); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &StatusChange{
+		TicketID:     ticket.ID,
+		TicketNumber: ticket.TicketNumber,
+		Subject:      ticket.Subject,
+		OldStatus:    ticket.Status,
+		NewStatus:    newStatus,
+		HandledBy:    assignee.Nama,
+	}, nil
+}
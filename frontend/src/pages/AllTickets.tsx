@@ -371,7 +371,7 @@ const AllTickets = () => {
                                     <span className="detail-label">Bukti Masalah</span>
                                     <span className="detail-value">
                                         <img
-                                            src={`http://localhost:8080/uploads/${selectedTicket.bukti_masalah}`}
+                                            src={`http://localhost:8080${selectedTicket.bukti_masalah_url}`}
                                             alt="Bukti Masalah"
                                             className="bukti-image"
                                         />
@@ -384,7 +384,7 @@ const AllTickets = () => {
                                     <span className="detail-label">Bukti Selesai</span>
                                     <span className="detail-value">
                                         <img
-                                            src={`http://localhost:8080/uploads/${selectedTicket.bukti_selesai}`}
+                                            src={`http://localhost:8080${selectedTicket.bukti_selesai_url}`}
                                             alt="Bukti Selesai"
                                             className="bukti-image"
                                         />
@@ -5,15 +5,28 @@ import { ticketService } from '../services/api';
 import type { Category } from '../services/api';
 import '../index.css';
 
+interface CategoryOption {
+    id: number;
+    label: string;
+}
+
+// Tickets can only be filed under leaf categories; label them with their full path
+const leafCategories = (categories: Category[], path: string[] = []): CategoryOption[] =>
+    categories.flatMap((cat) =>
+        cat.children && cat.children.length > 0
+            ? leafCategories(cat.children, [...path, cat.name])
+            : [{ id: cat.id, label: [...path, cat.name].join(' › ') }]
+    );
+
 const CreateTicket = () => {
     const { user, logout, isAdmin } = useAuth();
     const navigate = useNavigate();
-    const [categories, setCategories] = useState<Category[]>([]);
+    const [categories, setCategories] = useState<CategoryOption[]>([]);
     const [loading, setLoading] = useState(false);
     const [formData, setFormData] = useState({
         subject: '',
         description: '',
-        category: '',
+        category_id: 0,
     });
     const [buktiFile, setBuktiFile] = useState<File | null>(null);
     const [error, setError] = useState<string | null>(null);
@@ -24,10 +37,10 @@ const CreateTicket = () => {
 
     const loadCategories = async () => {
         try {
-            const data = await ticketService.getCategories();
-            setCategories(data);
-            if (data.length > 0) {
-                setFormData((prev) => ({ ...prev, category: data[0].name }));
+            const options = leafCategories(await ticketService.getCategories());
+            setCategories(options);
+            if (options.length > 0) {
+                setFormData((prev) => ({ ...prev, category_id: options[0].id }));
             }
         } catch (err) {
             console.error('Error loading categories:', err);
@@ -98,12 +111,12 @@ const CreateTicket = () => {
                             <div className="form-group">
                                 <label className="form-label">Kategori</label>
                                 <select
-                                    value={formData.category}
-                                    onChange={(e) => setFormData({ ...formData, category: e.target.value })}
+                                    value={formData.category_id}
+                                    onChange={(e) => setFormData({ ...formData, category_id: Number(e.target.value) })}
                                     className="form-select"
                                 >
                                     {categories.map((cat) => (
-                                        <option key={cat.id} value={cat.name}>{cat.name}</option>
+                                        <option key={cat.id} value={cat.id}>{cat.label}</option>
                                     ))}
                                 </select>
                             </div>
@@ -127,7 +127,7 @@ const TicketDetail = () => {
                         <div style={{ padding: '20px', borderTop: '1px solid var(--gray-200)' }}>
                             <p className="info-label" style={{ marginBottom: '12px' }}>📷 Bukti Masalah</p>
                             <img
-                                src={`http://localhost:8080/uploads/${ticket.bukti_masalah}`}
+                                src={`http://localhost:8080${ticket.bukti_masalah_url}`}
                                 alt="Bukti masalah"
                                 style={{ maxWidth: '100%', borderRadius: '8px' }}
                             />
@@ -138,7 +138,7 @@ const TicketDetail = () => {
                         <div style={{ padding: '20px', borderTop: '1px solid var(--gray-200)' }}>
                             <p className="info-label" style={{ marginBottom: '12px' }}>✅ Bukti Selesai</p>
                             <img
-                                src={`http://localhost:8080/uploads/${ticket.bukti_selesai}`}
+                                src={`http://localhost:8080${ticket.bukti_selesai_url}`}
                                 alt="Bukti selesai"
                                 style={{ maxWidth: '100%', borderRadius: '8px' }}
                             />
@@ -192,7 +192,7 @@ const Tickets = () => {
                                     <span className="detail-label">Bukti Masalah</span>
                                     <span className="detail-value">
                                         <img
-                                            src={`http://localhost:8080/uploads/${selectedTicket.bukti_masalah}`}
+                                            src={`http://localhost:8080${selectedTicket.bukti_masalah_url}`}
                                             alt="Bukti Masalah"
                                             className="bukti-image"
                                         />
@@ -205,7 +205,7 @@ const Tickets = () => {
                                     <span className="detail-label">Bukti Selesai</span>
                                     <span className="detail-value">
                                         <img
-                                            src={`http://localhost:8080/uploads/${selectedTicket.bukti_selesai}`}
+                                            src={`http://localhost:8080${selectedTicket.bukti_selesai_url}`}
                                             alt="Bukti Selesai"
                                             className="bukti-image"
                                         />
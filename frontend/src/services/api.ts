@@ -42,6 +42,10 @@ export interface Ticket {
     dikerjakan_oleh: string | null;
     bukti_masalah: string | null;
     bukti_selesai: string | null;
+    bukti_masalah_url: string | null;
+    bukti_selesai_url: string | null;
+    bukti_masalah_thumb_url: string | null;
+    bukti_selesai_thumb_url: string | null;
     created_at: string;
     updated_at: string;
     resolved_at: string | null;
@@ -49,8 +53,10 @@ export interface Ticket {
 
 export interface Category {
     id: number;
+    parent_id: number | null;
     name: string;
     description: string;
+    children?: Category[];
 }
 
 export interface DashboardStats {
@@ -75,7 +81,7 @@ export const ticketService = {
     },
 
     // Create new ticket
-    createTicket: async (data: { subject: string; description: string; category: string }): Promise<Ticket> => {
+    createTicket: async (data: { subject: string; description: string; category_id: number }): Promise<Ticket> => {
         const response = await api.post('/tickets', data);
         return response.data;
     },
@@ -104,7 +110,7 @@ export const ticketService = {
         });
     },
 
-    // Get categories
+    // Get active categories as a tree
     getCategories: async (): Promise<Category[]> => {
         const response = await api.get('/categories');
         return response.data;
@@ -167,4 +173,35 @@ export const adminService = {
     },
 };
 
+export interface TelegramLink {
+    linked: boolean;
+    telegram_username?: string;
+    linked_at?: string;
+}
+
+export interface TelegramLinkCode {
+    code: string;
+    url: string;
+    expires_at: string;
+}
+
+export const telegramService = {
+    // Get the Telegram account linked to the current user
+    getLink: async (): Promise<TelegramLink> => {
+        const response = await api.get('/telegram/link');
+        return response.data;
+    },
+
+    // Generate a bot link (t.me/<bot>?start=<code>) that links the user's chat
+    createLink: async (): Promise<TelegramLinkCode> => {
+        const response = await api.post('/telegram/link');
+        return response.data;
+    },
+
+    // Stop personal Telegram notifications
+    unlink: async (): Promise<void> => {
+        await api.delete('/telegram/link');
+    },
+};
+
 export default api;